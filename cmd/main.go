@@ -1,17 +1,22 @@
 package main
 
 import (
+	"context"
 	"log"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
 	"github.com/saifoelloh/ranger/internal/config"
 	handler "github.com/saifoelloh/ranger/internal/handler"
+	"github.com/saifoelloh/ranger/internal/jwk"
 	"github.com/saifoelloh/ranger/internal/middleware"
+	"github.com/saifoelloh/ranger/internal/notifier"
+	"github.com/saifoelloh/ranger/internal/ratelimit"
+	"github.com/saifoelloh/ranger/internal/rbac"
 	"github.com/saifoelloh/ranger/internal/redis"
 	repository "github.com/saifoelloh/ranger/internal/repositories"
 	service "github.com/saifoelloh/ranger/internal/services"
+	"github.com/saifoelloh/ranger/internal/sso"
 	"github.com/saifoelloh/ranger/pkg/errors"
 )
 
@@ -42,25 +47,111 @@ func main() {
 	// Initialize Repositories
 	userRepo := repository.NewUserRepository(db)
 	sessionRepo := repository.NewSessionRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+	passwordHistoryRepo := repository.NewPasswordHistoryRepository(db)
+	pinRepo := repository.NewPinRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
 
 	// Redis
 	limiterCfg := redis.RateLimiterConfig{
-		MaxAttempts:     3,
-		DelayPerAttempt: 10 * time.Second,
-		LockoutDuration: 10 * time.Minute,
+		Attempts: redis.RateLimit(cfg.RateLimitAttempts),
+		Login: map[redis.LoginDimension]redis.RateLimit{
+			redis.LoginDimensionAccount: redis.RateLimit(cfg.LoginRateLimitAccount),
+			redis.LoginDimensionIP:      redis.RateLimit(cfg.LoginRateLimitIP),
+			redis.LoginDimensionSubnet:  redis.RateLimit(cfg.LoginRateLimitSubnet),
+			redis.LoginDimensionDevice:  redis.RateLimit(cfg.LoginRateLimitDevice),
+		},
+		Backoff: redis.Backoff{
+			MaxDelay:          cfg.RateLimitMaxDelay,
+			OffenseWindow:     cfg.RateLimitOffenseWindow,
+			LockoutMultiplier: cfg.RateLimitLockoutMultiplier,
+			MaxLockout:        cfg.RateLimitMaxLockout,
+		},
+		FailOpen: cfg.RateLimitFailOpen,
 	}
 	redisClient := redis.NewRedisClient(rdb)
 	rateLimiterRepo := redis.NewRateLimiterRepository(redisClient, limiterCfg)
 	tokenCacheRepo := redis.NewTokenRepository(redisClient)
+	mfaChallengeRepo := redis.NewMFAChallengeRepository(redisClient)
+	otpRepo := redis.NewOTPRepository(redisClient)
+	passwordResetRepo := redis.NewPasswordResetRepository(redisClient)
+	ssoNonceRepo := redis.NewSSONonceRepository(redisClient)
+	deviceChallengeRepo := redis.NewDeviceChallengeRepository(redisClient)
+
+	// Notifications
+	sender, err := notifier.NewSender(cfg)
+	if err != nil {
+		errors.LogAndPanic(err)
+	}
+
+	// JWT signing keys
+	keyManager, err := jwk.NewKeyManager(cfg)
+	if err != nil {
+		errors.LogAndPanic(err)
+	}
+	go keyManager.StartRotation(context.Background(), cfg.JwtKeyReloadInterval)
+
+	ssoVerifiers, err := sso.NewVerifiers(cfg, ssoNonceRepo)
+	if err != nil {
+		errors.LogAndPanic(err)
+	}
+
+	permissions, err := rbac.LoadMatrix(cfg.PermissionMatrixFile)
+	if err != nil {
+		errors.LogAndPanic(errors.InternalServerError(
+			errors.WithScope("main"),
+			errors.WithLocation("rbac.LoadMatrix"),
+			errors.WithMessage("failed to load permission matrix"),
+			errors.WithDetail(err.Error()),
+		))
+	}
+
+	apiQuotas, err := ratelimit.LoadPolicy(cfg.APIRateLimitFile)
+	if err != nil {
+		errors.LogAndPanic(errors.InternalServerError(
+			errors.WithScope("main"),
+			errors.WithLocation("ratelimit.LoadPolicy"),
+			errors.WithMessage("failed to load api rate limit policy"),
+			errors.WithDetail(err.Error()),
+		))
+	}
 
 	// Initialize Services
-	authService := service.NewAuthService(cfg, userRepo, sessionRepo, rateLimiterRepo, tokenCacheRepo)
+	mfaService := service.NewMFAService(cfg, userRepo, mfaRepo, rateLimiterRepo)
+	authService := service.NewAuthService(cfg, keyManager, userRepo, sessionRepo, refreshTokenRepo, rateLimiterRepo, tokenCacheRepo, mfaChallengeRepo, mfaService, ssoVerifiers, permissions)
+	sessionService := service.NewSessionService(sessionRepo, authService)
+	deviceService := service.NewDeviceService(cfg, userRepo, sessionRepo, deviceChallengeRepo, authService)
+	otpService := service.NewOTPService(cfg, userRepo, otpRepo, sender, authService)
+	userService := service.NewUserService(cfg, userRepo, otpRepo, rateLimiterRepo, sender)
+	ssoService := service.NewSSOService(cfg, userRepo, ssoNonceRepo, rateLimiterRepo, ssoVerifiers, authService)
+	pinService := service.NewPinService(cfg, pinRepo, rateLimiterRepo, authService)
+	passwordService := service.NewPasswordService(cfg, userRepo, passwordHistoryRepo, passwordResetRepo, rateLimiterRepo, sender, authService)
+	lockoutService := service.NewLockoutService(userRepo, sessionRepo, auditRepo, rateLimiterRepo)
 
 	// Initialize Handlers
 	authHandler := handler.NewAuthHandler(authService)
+	jwksHandler := handler.NewJWKSHandler(keyManager)
+	sessionHandler := handler.NewSessionHandler(sessionService)
+	deviceHandler := handler.NewDeviceHandler(deviceService)
+	mfaHandler := handler.NewMFAHandler(mfaService)
+	otpHandler := handler.NewOTPHandler(otpService)
+	passwordHandler := handler.NewPasswordHandler(passwordService)
+	pinHandler := handler.NewPinHandler(pinService)
+	userHandler := handler.NewUserHandler(userService)
+	ssoHandler := handler.NewSSOHandler(ssoService)
+	lockoutHandler := handler.NewLockoutHandler(lockoutService)
 
 	// Setup Router
 	router := gin.Default()
+	if err := middleware.TrustProxies(router, cfg.TrustedProxies, cfg.TrustedPlatform); err != nil {
+		errors.LogAndPanic(errors.InternalServerError(
+			errors.WithScope("main"),
+			errors.WithLocation("middleware.TrustProxies"),
+			errors.WithMessage("invalid trusted proxies"),
+			errors.WithDetail(err.Error()),
+		))
+	}
 	router.Use(middleware.ErrorHandler())
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
@@ -77,10 +168,49 @@ func main() {
 
 	// Routes
 	router.POST("/login", authHandler.Login)
+	router.POST("/login/mfa", authHandler.LoginMFA)
+	router.POST("/admin/login", authHandler.AdminLogin)
+	router.POST("/admin/mfa/totp/enroll", middleware.AuthenticateMFAEnrollment(authService), mfaHandler.Enroll)
+	router.POST("/admin/mfa/totp/activate", middleware.AuthenticateMFAEnrollment(authService), mfaHandler.Activate)
+	router.POST("/oauth/introspect", middleware.AuthenticateClient(cfg.IntrospectionClients), authHandler.Introspect)
+	router.POST("/token/refresh", authHandler.RefreshToken)
+	router.POST("/logout", authHandler.Logout)
+	router.POST("/logout/all", authHandler.LogoutAll)
+	router.POST("/device/challenge", middleware.RateLimitClientIP(rateLimiterRepo, apiQuotas), deviceHandler.LoginChallenge)
+	router.POST("/device/login", deviceHandler.Login)
+	router.POST("/otp/request", otpHandler.Request)
+	router.POST("/otp/verify", otpHandler.Verify)
+	router.POST("/password/forgot", passwordHandler.Forgot)
+	router.POST("/password/reset", passwordHandler.Reset)
+	router.POST("/register", middleware.RateLimitClientIP(rateLimiterRepo, apiQuotas), userHandler.Register)
+	router.POST("/register/verify-email", userHandler.VerifyEmail)
+	router.POST("/register/resend-verification", userHandler.ResendVerification)
+	router.POST("/sso/nonce", ssoHandler.Nonce)
+	router.GET("/.well-known/jwks.json", jwksHandler.JWKS)
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	authenticated := router.Group("/", middleware.Authenticate(authService), middleware.RateLimitAPI(rateLimiterRepo, apiQuotas))
+	authenticated.GET("/me", authHandler.Me)
+	authenticated.GET("/sessions", sessionHandler.List)
+	authenticated.GET("/sessions/:id", sessionHandler.Get)
+	authenticated.DELETE("/sessions/:id", sessionHandler.Revoke)
+	authenticated.POST("/device/step-up/challenge", deviceHandler.StepUpChallenge)
+	authenticated.POST("/device/step-up", deviceHandler.StepUp)
+	authenticated.POST("/mfa/totp/enroll", mfaHandler.Enroll)
+	authenticated.POST("/mfa/totp/activate", mfaHandler.Activate)
+	authenticated.POST("/mfa/totp/disable", mfaHandler.Disable)
+	authenticated.POST("/password/change", passwordHandler.Change)
+	authenticated.POST("/pin", pinHandler.Set)
+	authenticated.PUT("/pin", pinHandler.Change)
+	authenticated.POST("/pin/verify", pinHandler.Verify)
+	authenticated.POST("/sso/link", ssoHandler.Link)
+	authenticated.GET("/sso/providers", ssoHandler.List)
+	authenticated.DELETE("/sso/:platform", ssoHandler.Unlink)
+	authenticated.GET("/admin/lockouts", middleware.RequirePermission(rbac.PermissionLockoutsRead), lockoutHandler.Get)
+	authenticated.DELETE("/admin/lockouts", middleware.RequirePermission(rbac.PermissionLockoutsManage), lockoutHandler.Clear)
+
 	// Run Server
 	router.Run(":" + cfg.AppPort)
 }
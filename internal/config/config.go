@@ -1,14 +1,18 @@
 package config
 
 import (
+	"net"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/redis/go-redis/v9"
+	"github.com/saifoelloh/ranger/internal/constant"
 )
 
 type Config struct {
-	AppPort string
+	AppPort         string
+	TrustedProxies  []string // IPs or CIDRs whose X-Forwarded-For is believed; empty trusts none
+	TrustedPlatform string   // header set by the edge platform with the client IP, e.g. CF-Connecting-IP
 
 	DBDriver   string
 	DBUser     string
@@ -26,6 +30,105 @@ type Config struct {
 	JwtSecret string
 	JwtExpiry time.Duration
 	JwtIssuer string
+
+	JwtKeysDir           string        // directory of <kid>.pem files; empty falls back to HS256 with JwtSecret
+	JwtSigningKeyID      string        // pins the signing key instead of picking the newest one
+	JwtKeyReloadInterval time.Duration // how often the key directory is re-read
+
+	RefreshTokenExpiry      time.Duration // sliding lifetime of a single refresh token
+	RefreshTokenMaxLifetime time.Duration // absolute lifetime counted from session creation
+
+	DeviceChallengeExpiry time.Duration
+	StepUpTokenExpiry     time.Duration
+
+	MFAEncryptionKey    string // encrypts TOTP secrets at rest
+	MFAChallengeExpiry  time.Duration
+	MFAEnrollmentExpiry time.Duration // lifetime of the token an admin without MFA enrolls with
+	TOTPIssuer          string
+	RecoveryCodeCount   int
+
+	NotifierDriver   string // log | file
+	NotifierFilePath string
+
+	OTPLength         int
+	OTPExpiry         time.Duration
+	OTPMaxAttempts    int
+	OTPResendCooldown time.Duration
+	VerifiedNumberTTL time.Duration
+
+	EmailVerificationExpiry   time.Duration
+	EmailVerificationCooldown time.Duration // between two verification codes sent to the same address
+
+	PasswordMinLength     int
+	PasswordResetExpiry   time.Duration
+	PasswordResetCooldown time.Duration
+	PasswordResetURL      string // link sent by email, the token is appended as ?token=
+	PasswordHistorySize   int    // previous passwords that may not be reused
+
+	PinLength int
+
+	GoogleClientIDs []string // accepted audiences of Google ID tokens
+	GoogleJWKSURL   string
+	AppleClientIDs  []string // accepted audiences of Apple ID tokens
+	AppleJWKSURL    string
+	SSOJWKSFile     string        // local JWKS used for Google and Apple instead of fetching, for offline testing
+	SSOJWKSCacheTTL time.Duration // how long fetched provider keys are trusted before refetching
+	SSORequireNonce bool
+	SSONonceExpiry  time.Duration // lifetime of the single-use nonces issued for ID tokens
+
+	FacebookAppID     string
+	FacebookAppSecret string
+	FacebookGraphURL  string
+	FacebookStubFile  string // local token to profile map used instead of the Graph API, for offline testing
+
+	PermissionMatrixFile string // JSON role to permissions map; empty uses the built-in matrix
+
+	AdminJwtExpiry          time.Duration
+	AdminSessionMaxLifetime time.Duration
+	AdminIPAllowlist        []*net.IPNet // empty allows every address; matched against the client IP resolved through TrustedProxies
+
+	RateLimitFailOpen          bool            // let attempts through when the rate limiter cannot reach Redis
+	RateLimitAttempts          RateLimitConfig // mfa, password, pin and email verification attempts
+	LoginRateLimitAccount      RateLimitConfig
+	LoginRateLimitIP           RateLimitConfig // counts failed logins only, a successful login is not counted but does not clear the failures either
+	LoginRateLimitSubnet       RateLimitConfig // counts failed logins only as well, size it for shared NATs
+	LoginRateLimitDevice       RateLimitConfig
+	RateLimitMaxDelay          time.Duration
+	RateLimitOffenseWindow     time.Duration // how long a lockout counts towards escalating the next one
+	RateLimitLockoutMultiplier int
+	RateLimitMaxLockout        time.Duration
+	APIRateLimitFile           string // JSON quota policy of the API rate limiter; empty uses the built-in policy
+
+	IntrospectionClients  map[string]string // client id to secret of services allowed to introspect tokens
+	IntrospectionCacheTTL time.Duration
+
+	SessionPolicy   SessionPolicyConfig            // applied when no override matches
+	SessionPolicies map[string]SessionPolicyConfig // keyed by user role or user type
+}
+
+// RateLimitConfig is the threshold of one attempt counter
+type RateLimitConfig struct {
+	MaxAttempts int
+	Window      time.Duration
+	Lockout     time.Duration // first lockout, escalated for repeat offenders
+	Delay       time.Duration // wait after the first attempt, doubled after every following one; 0 disables
+}
+
+type SessionPolicyConfig struct {
+	Policy      constant.SessionPolicy
+	MaxSessions int // only used by SessionPolicyMaxSessions
+}
+
+// SessionPolicyFor returns the concurrent-session policy of a user, preferring a
+// role override over a user type override over the default
+func (c Config) SessionPolicyFor(role string, userType constant.UserType) SessionPolicyConfig {
+	if policy, ok := c.SessionPolicies[role]; ok && role != "" {
+		return policy
+	}
+	if policy, ok := c.SessionPolicies[string(userType)]; ok {
+		return policy
+	}
+	return c.SessionPolicy
 }
 
 var (
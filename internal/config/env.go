@@ -3,12 +3,19 @@ package config
 import (
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/saifoelloh/ranger/internal/constant"
 )
 
+// minSecretLength is the shortest secret accepted for deriving an encryption key
+const minSecretLength = 32
+
 func LoadConfig() Config {
 	err := godotenv.Load()
 	if err != nil {
@@ -32,7 +39,9 @@ func LoadConfig() Config {
 	}
 
 	return Config{
-		AppPort: appPort,
+		AppPort:         appPort,
+		TrustedProxies:  parseList(getEnv("TRUSTED_PROXIES", "")),
+		TrustedPlatform: getEnv("TRUSTED_PLATFORM", ""),
 
 		DBDriver:   getEnv("DB_DRIVER", "postgres"),
 		DBUser:     dbUser,
@@ -49,6 +58,81 @@ func LoadConfig() Config {
 
 		JwtSecret: getEnv("JWT_SECRET", "default-secret-key"),
 		JwtExpiry: parseDuration(getEnv("JWT_EXPIRY", "15m")),
+		JwtIssuer: getEnv("JWT_ISSUER", ""),
+
+		JwtKeysDir:           getEnv("JWT_KEYS_DIR", ""),
+		JwtSigningKeyID:      getEnv("JWT_SIGNING_KEY_ID", ""),
+		JwtKeyReloadInterval: parseDuration(getEnv("JWT_KEY_RELOAD_INTERVAL", "5m")),
+
+		RefreshTokenExpiry:      parseDuration(getEnv("REFRESH_TOKEN_EXPIRY", "720h")),
+		RefreshTokenMaxLifetime: parseDuration(getEnv("REFRESH_TOKEN_MAX_LIFETIME", "2160h")),
+
+		DeviceChallengeExpiry: parseDuration(getEnv("DEVICE_CHALLENGE_EXPIRY", "2m")),
+		StepUpTokenExpiry:     parseDuration(getEnv("STEP_UP_TOKEN_EXPIRY", "5m")),
+
+		MFAEncryptionKey:    parseSecret("MFA_ENCRYPTION_KEY", getEnv("MFA_ENCRYPTION_KEY", "")),
+		MFAChallengeExpiry:  parseDuration(getEnv("MFA_CHALLENGE_EXPIRY", "5m")),
+		MFAEnrollmentExpiry: parseDuration(getEnv("MFA_ENROLLMENT_EXPIRY", "15m")),
+		TOTPIssuer:          getEnv("TOTP_ISSUER", "Ranger"),
+		RecoveryCodeCount:   parseInt(getEnv("RECOVERY_CODE_COUNT", "10")),
+
+		NotifierDriver:   getEnv("NOTIFIER_DRIVER", "log"),
+		NotifierFilePath: getEnv("NOTIFIER_FILE_PATH", "notifications.log"),
+
+		OTPLength:         parseInt(getEnv("OTP_LENGTH", "6")),
+		OTPExpiry:         parseDuration(getEnv("OTP_EXPIRY", "5m")),
+		OTPMaxAttempts:    parseInt(getEnv("OTP_MAX_ATTEMPTS", "5")),
+		OTPResendCooldown: parseDuration(getEnv("OTP_RESEND_COOLDOWN", "1m")),
+		VerifiedNumberTTL: parseDuration(getEnv("VERIFIED_NUMBER_TTL", "15m")),
+
+		EmailVerificationExpiry:   parseDuration(getEnv("EMAIL_VERIFICATION_EXPIRY", "24h")),
+		EmailVerificationCooldown: parseDuration(getEnv("EMAIL_VERIFICATION_COOLDOWN", "1m")),
+
+		PasswordMinLength:     parseInt(getEnv("PASSWORD_MIN_LENGTH", "8")),
+		PasswordResetExpiry:   parseDuration(getEnv("PASSWORD_RESET_EXPIRY", "30m")),
+		PasswordResetCooldown: parseDuration(getEnv("PASSWORD_RESET_COOLDOWN", "1m")),
+		PasswordResetURL:      getEnv("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+		PasswordHistorySize:   parseInt(getEnv("PASSWORD_HISTORY_SIZE", "5")),
+
+		PinLength: parseInt(getEnv("PIN_LENGTH", "6")),
+
+		GoogleClientIDs: parseList(getEnv("GOOGLE_CLIENT_IDS", "")),
+		GoogleJWKSURL:   getEnv("GOOGLE_JWKS_URL", "https://www.googleapis.com/oauth2/v3/certs"),
+		AppleClientIDs:  parseList(getEnv("APPLE_CLIENT_IDS", "")),
+		AppleJWKSURL:    getEnv("APPLE_JWKS_URL", "https://appleid.apple.com/auth/keys"),
+		SSOJWKSFile:     getEnv("SSO_JWKS_FILE", ""),
+		SSOJWKSCacheTTL: parseDuration(getEnv("SSO_JWKS_CACHE_TTL", "1h")),
+		SSORequireNonce: parseBool(getEnv("SSO_REQUIRE_NONCE", "true")),
+		SSONonceExpiry:  parseDuration(getEnv("SSO_NONCE_EXPIRY", "10m")),
+
+		FacebookAppID:     getEnv("FACEBOOK_APP_ID", ""),
+		FacebookAppSecret: getEnv("FACEBOOK_APP_SECRET", ""),
+		FacebookGraphURL:  getEnv("FACEBOOK_GRAPH_URL", "https://graph.facebook.com"),
+		FacebookStubFile:  getEnv("FACEBOOK_STUB_FILE", ""),
+
+		PermissionMatrixFile: getEnv("PERMISSION_MATRIX_FILE", ""),
+
+		AdminJwtExpiry:          parseDuration(getEnv("ADMIN_JWT_EXPIRY", "15m")),
+		AdminSessionMaxLifetime: parseDuration(getEnv("ADMIN_SESSION_MAX_LIFETIME", "12h")),
+		AdminIPAllowlist:        parseCIDRs(getEnv("ADMIN_IP_ALLOWLIST", "")),
+
+		RateLimitFailOpen:          parseFailMode(getEnv("RATE_LIMIT_FAIL_MODE", "closed")),
+		RateLimitAttempts:          parseRateLimit(getEnv("RATE_LIMIT_ATTEMPTS", "5/15m/10m/1s")),
+		LoginRateLimitAccount:      parseRateLimit(getEnv("LOGIN_RATE_LIMIT_ACCOUNT", "5/15m/15m/1s")),
+		LoginRateLimitIP:           parseRateLimit(getEnv("LOGIN_RATE_LIMIT_IP", "20/15m/30m")),
+		LoginRateLimitSubnet:       parseRateLimit(getEnv("LOGIN_RATE_LIMIT_SUBNET", "100/15m/30m")),
+		LoginRateLimitDevice:       parseRateLimit(getEnv("LOGIN_RATE_LIMIT_DEVICE", "10/15m/15m/1s")),
+		RateLimitMaxDelay:          parseDuration(getEnv("RATE_LIMIT_MAX_DELAY", "30s")),
+		RateLimitOffenseWindow:     parseDuration(getEnv("RATE_LIMIT_OFFENSE_WINDOW", "24h")),
+		RateLimitLockoutMultiplier: parseMultiplier(getEnv("RATE_LIMIT_LOCKOUT_MULTIPLIER", "2")),
+		RateLimitMaxLockout:        parseDuration(getEnv("RATE_LIMIT_MAX_LOCKOUT", "24h")),
+		APIRateLimitFile:           getEnv("API_RATE_LIMIT_FILE", ""),
+
+		IntrospectionClients:  parseCredentials(getEnv("INTROSPECTION_CLIENTS", "")),
+		IntrospectionCacheTTL: parseDuration(getEnv("INTROSPECTION_CACHE_TTL", "10s")),
+
+		SessionPolicy:   parseSessionPolicy(getEnv("SESSION_POLICY", string(constant.SessionPolicySingle))),
+		SessionPolicies: parseSessionPolicies(getEnv("SESSION_POLICY_OVERRIDES", "")),
 	}
 }
 
@@ -69,3 +153,150 @@ func parseDuration(s string) time.Duration {
 	}
 	return d
 }
+
+// Helper: Parse int
+func parseInt(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		panic("Invalid integer: " + s)
+	}
+	return n
+}
+
+// Helper: Parse bool
+func parseBool(s string) bool {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		panic("Invalid boolean: " + s)
+	}
+	return b
+}
+
+// Helper: Parse a comma separated list, dropping empty entries
+func parseList(s string) []string {
+	list := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Helper: Parse a comma separated list of CIDRs, a bare IP is taken as a single address
+func parseCIDRs(s string) []*net.IPNet {
+	networks := []*net.IPNet{}
+	for _, item := range parseList(s) {
+		if !strings.Contains(item, "/") {
+			if ip := net.ParseIP(item); ip != nil && ip.To4() != nil {
+				item += "/32"
+			} else {
+				item += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			panic("Invalid CIDR: " + item)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// Helper: Parse "ID:SECRET,ID:SECRET" client credentials
+func parseCredentials(s string) map[string]string {
+	credentials := map[string]string{}
+	for _, entry := range parseList(s) {
+		id, secret, ok := strings.Cut(entry, ":")
+		if !ok || id == "" || secret == "" {
+			panic("Invalid client credentials: " + id)
+		}
+		credentials[id] = secret
+	}
+	return credentials
+}
+
+// Helper: Parse "open" or "closed", reporting whether to fail open
+func parseFailMode(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "open":
+		return true
+	case "closed":
+		return false
+	}
+	panic("Invalid fail mode: " + s)
+}
+
+// Helper: Parse "MAX_ATTEMPTS/WINDOW/LOCKOUT[/DELAY]" such as "5/15m/15m/1s"
+func parseRateLimit(s string) RateLimitConfig {
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	if len(parts) != 3 && len(parts) != 4 {
+		panic("Invalid rate limit: " + s)
+	}
+
+	limit := RateLimitConfig{
+		MaxAttempts: parseInt(parts[0]),
+		Window:      parseDuration(parts[1]),
+		Lockout:     parseDuration(parts[2]),
+	}
+	if len(parts) == 4 {
+		limit.Delay = parseDuration(parts[3])
+	}
+	if limit.MaxAttempts <= 0 || limit.Window <= 0 || limit.Lockout <= 0 || limit.Delay < 0 {
+		panic("Invalid rate limit: " + s)
+	}
+	return limit
+}
+
+// Helper: Require a secret long enough to derive a key from, the value is never echoed
+func parseSecret(name, s string) string {
+	if len(s) < minSecretLength {
+		panic("Invalid " + name + ": must be at least " + strconv.Itoa(minSecretLength) + " characters")
+	}
+	return s
+}
+
+// Helper: Parse a lockout multiplier, lockouts may never shrink
+func parseMultiplier(s string) int {
+	n := parseInt(s)
+	if n < 1 {
+		panic("Invalid multiplier: " + s)
+	}
+	return n
+}
+
+// Helper: Parse a session policy such as "SINGLE" or "MAX_SESSIONS:3"
+func parseSessionPolicy(s string) SessionPolicyConfig {
+	name, max, _ := strings.Cut(strings.TrimSpace(s), ":")
+	policy := SessionPolicyConfig{Policy: constant.SessionPolicy(strings.ToUpper(name))}
+
+	switch policy.Policy {
+	case constant.SessionPolicySingle, constant.SessionPolicyPerDevice, constant.SessionPolicyUnlimited:
+	case constant.SessionPolicyMaxSessions:
+		n, err := strconv.Atoi(max)
+		if err != nil || n < 1 {
+			panic("Invalid session policy: " + s)
+		}
+		policy.MaxSessions = n
+	default:
+		panic("Invalid session policy: " + s)
+	}
+
+	return policy
+}
+
+// Helper: Parse "KEY=POLICY,KEY=POLICY" where KEY is a user role or user type
+func parseSessionPolicies(s string) map[string]SessionPolicyConfig {
+	policies := map[string]SessionPolicyConfig{}
+	for _, entry := range strings.Split(s, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			panic("Invalid session policy override: " + entry)
+		}
+		policies[strings.TrimSpace(key)] = parseSessionPolicy(value)
+	}
+	return policies
+}
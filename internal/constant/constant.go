@@ -34,3 +34,56 @@ const (
 	SSOPlatformApple    SSOPlatform = "APPLE"
 	SSOPlatformFacebook SSOPlatform = "FACEBOOK"
 )
+
+type DeviceClass string
+
+const (
+	DeviceClassMobile  DeviceClass = "MOBILE"
+	DeviceClassTablet  DeviceClass = "TABLET"
+	DeviceClassDesktop DeviceClass = "DESKTOP"
+	DeviceClassUnknown DeviceClass = "UNKNOWN"
+)
+
+type SessionPolicy string
+
+const (
+	SessionPolicySingle      SessionPolicy = "SINGLE"
+	SessionPolicyPerDevice   SessionPolicy = "PER_DEVICE_CLASS"
+	SessionPolicyMaxSessions SessionPolicy = "MAX_SESSIONS"
+	SessionPolicyUnlimited   SessionPolicy = "UNLIMITED"
+)
+
+// TokenScope marks what a step-up token has been issued for
+type TokenScope string
+
+const (
+	ScopeStepUpDevice  TokenScope = "step_up:device"
+	ScopeStepUpPin     TokenScope = "step_up:pin"
+	ScopePasswordReset TokenScope = "password_reset"
+	ScopeMFAEnrollment TokenScope = "mfa:enroll"
+)
+
+// TokenType is the "typ" header that tells apart the kinds of tokens signed with the same keys
+type TokenType string
+
+const (
+	TokenTypeAccess        TokenType = "at+jwt" // RFC 9068
+	TokenTypePasswordReset TokenType = "password-reset+jwt"
+	TokenTypeStepUp        TokenType = "step-up+jwt"
+	TokenTypeMFAEnrollment TokenType = "mfa-enrollment+jwt"
+	TokenTypeJWT           TokenType = "JWT" // untyped, only accepted from access tokens issued before typing
+)
+
+type ChallengePurpose string
+
+const (
+	ChallengePurposeLogin  ChallengePurpose = "login"
+	ChallengePurposeStepUp ChallengePurpose = "step_up"
+)
+
+type OTPAction string
+
+const (
+	OTPActionLogin       OTPAction = "LOGIN"
+	OTPActionVerifyPhone OTPAction = "VERIFY_PHONE"
+)
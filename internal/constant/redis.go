@@ -5,6 +5,8 @@ type KeyPrefix string
 
 const (
 	RequestRateLimit       KeyPrefix = "REQUEST_RATE_LIMIT"
+	ResendEmailCode        KeyPrefix = "RESEND_EMAIL_CODE"
+	SSONonce               KeyPrefix = "SSO_NONCE"
 	PendingOtpVerification KeyPrefix = "PENDING_OTP_VERIFICATION"
 	VerifiedNumber         KeyPrefix = "VERIFIED_NUMBER"
 	RequestChangePassword  KeyPrefix = "REQUEST_CHANGE_PASSWORD"
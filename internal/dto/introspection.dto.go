@@ -0,0 +1,21 @@
+package dto
+
+type IntrospectionRequest struct {
+	Token         string `form:"token" json:"token"`
+	TokenTypeHint string `form:"token_type_hint" json:"token_type_hint"`
+}
+
+// IntrospectionResponse follows RFC 7662, inactive tokens only carry active=false
+type IntrospectionResponse struct {
+	Active      bool     `json:"active"`
+	Sub         string   `json:"sub,omitempty"`
+	SessionID   string   `json:"session_id,omitempty"`
+	TokenType   string   `json:"token_type,omitempty"`
+	Exp         int64    `json:"exp,omitempty"`
+	Iat         int64    `json:"iat,omitempty"`
+	Iss         string   `json:"iss,omitempty"`
+	UserType    string   `json:"user_type,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Scope       string   `json:"scope,omitempty"` // space separated, as in RFC 7662
+	Permissions []string `json:"permissions,omitempty"`
+}
@@ -0,0 +1,29 @@
+package dto
+
+// LockoutQuery selects the lockouts of a user or of an IP address, exactly one must be set
+type LockoutQuery struct {
+	UserID string `form:"user_id"`
+	IP     string `form:"ip"`
+	Reason string `form:"reason"` // recorded in the audit trail when clearing
+}
+
+type LockoutCounterResponse struct {
+	Dimension   string `json:"dimension"`
+	Subject     string `json:"subject"`
+	Attempts    int    `json:"attempts"`
+	MaxAttempts int    `json:"max_attempts"`
+	Locked      bool   `json:"locked"`
+	AtThreshold bool   `json:"at_threshold"` // not locked yet, the next attempt is rejected and locks
+	RetryAfter  int64  `json:"retry_after"`  // seconds until the lockout or the progressive delay ends
+	NextLockout int64  `json:"next_lockout"` // seconds the next attempt locks for, at the threshold
+	ResetIn     int64  `json:"reset_in"`     // seconds until the attempt count expires
+	Offenses    int    `json:"offenses"`
+}
+
+type LockoutResponse struct {
+	UserID   string                   `json:"user_id,omitempty"`
+	IP       string                   `json:"ip,omitempty"`
+	Locked   bool                     `json:"locked"`
+	Tripped  []string                 `json:"tripped"` // dimensions currently locked
+	Counters []LockoutCounterResponse `json:"counters"`
+}
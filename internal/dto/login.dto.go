@@ -1,6 +1,8 @@
 package dto
 
 import (
+	"time"
+
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/saifoelloh/ranger/internal/constant"
 )
@@ -8,8 +10,8 @@ import (
 type LoginRequest struct {
 	Email       *string               `json:"email"`
 	Password    *string               `json:"password"`
-	SSOID       *string               `json:"sso_id"`
 	SSOPlatform *constant.SSOPlatform `json:"sso_platform"`
+	IDToken     string                `json:"id_token"` // provider token proving the sso identity
 	Device      string                `json:"device"`
 	MacAddress  string                `json:"mac_address"`
 	PublicKey   string                `json:"public_key"`
@@ -18,8 +20,9 @@ type LoginRequest struct {
 type LoginInput struct {
 	Email         *string               `json:"email"`
 	Password      *string               `json:"password"`
-	SSOID         *string               `json:"sso_id"`
+	SSOID         *string               `json:"sso_id"` // set from the verified token, never from the client
 	SSOPlatform   *constant.SSOPlatform `json:"sso_platform"`
+	IDToken       string                `json:"-"`
 	Device        string                `json:"device"`
 	MacAddress    string                `json:"mac_address"`
 	PublicKey     string                `json:"public_key"`
@@ -27,27 +30,50 @@ type LoginInput struct {
 	IP            string                `json:"ip"`
 	Location      string                `json:"location"`
 	ClientVersion string                `json:"client_version"`
+	DeviceClass   constant.DeviceClass  `json:"device_class"`
+	Admin         bool                  `json:"admin"` // came through the admin login
 }
 
 type LoginResponse struct {
-	SessionID    string `json:"session_id"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	SessionID    string `json:"session_id,omitempty"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+
+	// Set instead of the tokens when the user still has to pass a second factor
+	MFARequired  bool       `json:"mfa_required,omitempty"`
+	MFAToken     string     `json:"mfa_token,omitempty"`
+	MFAExpiresAt *time.Time `json:"mfa_expires_at,omitempty"`
+
+	// Set instead of the tokens when an admin has to enroll a second factor first. The token
+	// only authenticates the /admin/mfa/totp endpoints, the admin signs in again once activated.
+	MFAEnrollmentRequired  bool       `json:"mfa_enrollment_required,omitempty"`
+	MFAEnrollmentToken     string     `json:"mfa_enrollment_token,omitempty"`
+	MFAEnrollmentExpiresAt *time.Time `json:"mfa_enrollment_expires_at,omitempty"`
 }
 
+// ClaimsVersion is bumped whenever the meaning of AppClaims changes, so downstream
+// services can tell old tokens apart. v1 tokens have no ver claim.
+const ClaimsVersion = 2
+
 type AppClaims struct {
-	UserID    string `json:"user_id"`
-	UserType  string `json:"user_type"`
-	UserToken string `json:"user_token"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	SessionID string `json:"session_id"`
+	UserID       string   `json:"user_id"`
+	UserType     string   `json:"user_type"`
+	UserToken    string   `json:"user_token"` // Deprecated: investor type kept for v1 consumers, use InvestorType
+	InvestorType string   `json:"investor_type,omitempty"`
+	FirstName    string   `json:"first_name"`
+	LastName     string   `json:"last_name"`
+	SessionID    string   `json:"session_id"`
+	Scopes       []string `json:"scp,omitempty"`
+	Role         string   `json:"role,omitempty"`
+	Permissions  []string `json:"perms,omitempty"`
+	Version      int      `json:"ver,omitempty"`
 	jwt.RegisteredClaims
 }
 
 type UserAgent struct {
-	Device     string `json:"device"`
-	Os         string `json:"os"`
-	Raw        string `json:"raw"`
-	RedisLabel string `json:"redis_label"`
+	Device      string               `json:"device"`
+	Os          string               `json:"os"`
+	DeviceClass constant.DeviceClass `json:"device_class"`
+	Raw         string               `json:"raw"`
+	RedisLabel  string               `json:"redis_label"`
 }
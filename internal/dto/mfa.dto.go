@@ -0,0 +1,32 @@
+package dto
+
+import "time"
+
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+type TOTPEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TOTPActivateResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFADisableResponse struct {
+	Message string `json:"message"`
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+// PendingMFALogin is what is kept between the first and the second login factor
+type PendingMFALogin struct {
+	UserID    string     `json:"user_id"`
+	Input     LoginInput `json:"input"`
+	CreatedAt time.Time  `json:"created_at"`
+}
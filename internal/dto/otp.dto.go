@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/saifoelloh/ranger/internal/constant"
+)
+
+type OTPRequest struct {
+	PhoneNumber string             `json:"phone_number"`
+	Channel     string             `json:"channel"`
+	Action      constant.OTPAction `json:"action"`
+}
+
+type OTPRequestResponse struct {
+	Message   string    `json:"message"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type OTPVerifyRequest struct {
+	PhoneNumber string             `json:"phone_number"`
+	Code        string             `json:"code"`
+	Action      constant.OTPAction `json:"action"`
+	Device      string             `json:"device"`
+	MacAddress  string             `json:"mac_address"`
+	PublicKey   string             `json:"public_key"`
+}
+
+type OTPVerifyResponse struct {
+	Verified bool           `json:"verified"`
+	Login    *LoginResponse `json:"login,omitempty"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword    string `json:"current_password"`
+	NewPassword        string `json:"new_password"`
+	KeepCurrentSession bool   `json:"keep_current_session"`
+}
+
+type PasswordResponse struct {
+	Message         string `json:"message"`
+	SessionsRevoked int64  `json:"sessions_revoked,omitempty"`
+}
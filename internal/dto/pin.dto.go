@@ -0,0 +1,18 @@
+package dto
+
+type SetPinRequest struct {
+	Pin string `json:"pin"`
+}
+
+type ChangePinRequest struct {
+	CurrentPin string `json:"current_pin"`
+	NewPin     string `json:"new_pin"`
+}
+
+type VerifyPinRequest struct {
+	Pin string `json:"pin"`
+}
+
+type PinResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,33 @@
+package dto
+
+type RegisterRequest struct {
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	Email       string `json:"email"`
+	PhoneNumber string `json:"phone_number"`
+	Password    string `json:"password"`
+	KnowFrom    string `json:"know_from"`
+}
+
+type RegisterResponse struct {
+	UserID              string `json:"user_id"`
+	Message             string `json:"message"`
+	PhoneNumberVerified bool   `json:"phone_number_verified"`
+}
+
+type VerifyEmailRequest struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+type VerifyEmailResponse struct {
+	Verified bool `json:"verified"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+type ResendVerificationResponse struct {
+	Message string `json:"message"`
+}
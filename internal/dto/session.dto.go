@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/saifoelloh/ranger/internal/constant"
+)
+
+type SessionResponse struct {
+	ID            string    `json:"id"`
+	Device        string    `json:"device"`
+	MacAddress    string    `json:"mac_address"`
+	DeviceName    string    `json:"device_name"`
+	Os            string    `json:"os"`
+	IP            string    `json:"ip"`
+	Location      string    `json:"location"`
+	ClientVersion string    `json:"client_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastSeenAt    time.Time `json:"last_seen_at"`
+	Current       bool      `json:"current"`
+}
+
+type RevokeSessionResponse struct {
+	Message string `json:"message"`
+}
+
+type DeviceChallengeRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+type DeviceChallengeResponse struct {
+	SessionID string    `json:"session_id"`
+	Challenge string    `json:"challenge"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type DeviceLoginRequest struct {
+	SessionID string `json:"session_id"`
+	Challenge string `json:"challenge"` // as issued, the signature covers it
+	Signature string `json:"signature"`
+}
+
+type DeviceLoginInput struct {
+	SessionID     string
+	Challenge     string
+	Signature     string
+	UserAgent     string
+	IP            string
+	Location      string
+	ClientVersion string
+	DeviceClass   constant.DeviceClass
+}
+
+type DeviceStepUpRequest struct {
+	Challenge string `json:"challenge"` // as issued, the signature covers it
+	Signature string `json:"signature"`
+}
+
+type StepUpResponse struct {
+	StepUpToken string    `json:"step_up_token"`
+	Scope       string    `json:"scope"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
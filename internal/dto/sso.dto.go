@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/saifoelloh/ranger/internal/constant"
+)
+
+// LinkSSORequest needs either the current password or a device step-up token of the same session
+type LinkSSORequest struct {
+	SSOPlatform     constant.SSOPlatform `json:"sso_platform"`
+	IDToken         string               `json:"id_token"`
+	CurrentPassword string               `json:"current_password"`
+	StepUpToken     string               `json:"step_up_token"`
+}
+
+type SSONonceResponse struct {
+	Nonce     string    `json:"nonce"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type SSOProviderResponse struct {
+	SSOPlatform constant.SSOPlatform `json:"sso_platform"`
+	Linked      bool                 `json:"linked"`
+	Primary     bool                 `json:"primary"` // the sso_sign_option of the account
+}
+
+type SSOProvidersResponse struct {
+	Providers []SSOProviderResponse `json:"providers"`
+}
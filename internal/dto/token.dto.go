@@ -0,0 +1,10 @@
+package dto
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutResponse struct {
+	Message         string `json:"message"`
+	SessionsRevoked int64  `json:"sessions_revoked"`
+}
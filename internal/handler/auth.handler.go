@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 
 	"github.com/gin-gonic/gin"
+	"github.com/saifoelloh/ranger/internal/constant"
 	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/internal/middleware"
 	service "github.com/saifoelloh/ranger/internal/services"
 	"github.com/saifoelloh/ranger/internal/utils"
 	"github.com/saifoelloh/ranger/pkg/errors"
@@ -19,11 +21,74 @@ func NewAuthHandler(authService *service.AuthService) *AuthHandler {
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
+	input, ok := bindLoginInput(c, "Login")
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.Login(c.Request.Context(), input)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *AuthHandler) AdminLogin(c *gin.Context) {
+	input, ok := bindLoginInput(c, "AdminLogin")
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.AdminLogin(c.Request.Context(), input)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+// bindLoginInput reads a login request body together with the client details of the request
+func bindLoginInput(c *gin.Context, location string) (dto.LoginInput, bool) {
 	var req dto.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.Error(errors.BadRequest(
 			errors.WithScope("AuthHandler"),
-			errors.WithLocation("Login.BindJSON"),
+			errors.WithLocation(location+".BindJSON"),
+			errors.WithMessage("invalid request body"),
+			errors.WithErrorCode("auth/invalid-json"),
+			errors.WithDetail(err.Error()),
+		))
+		return dto.LoginInput{}, false
+	}
+
+	uniqueLable := utils.GetUniqueLabel(req.Email, nil)
+	formattedUserAgent, deviceClass := describeUserAgent(c, uniqueLable)
+
+	return dto.LoginInput{
+		Email:         req.Email,
+		Password:      req.Password,
+		SSOPlatform:   req.SSOPlatform,
+		IDToken:       req.IDToken,
+		Device:        req.Device,
+		MacAddress:    req.MacAddress,
+		PublicKey:     req.PublicKey,
+		UserAgent:     formattedUserAgent,
+		IP:            c.ClientIP(),
+		Location:      c.GetHeader("X-Location"),
+		ClientVersion: c.GetHeader("x-client-version"),
+		DeviceClass:   deviceClass,
+	}, true
+}
+
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req dto.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(
+			errors.WithScope("AuthHandler"),
+			errors.WithLocation("RefreshToken.BindJSON"),
 			errors.WithMessage("invalid request body"),
 			errors.WithErrorCode("auth/invalid-json"),
 			errors.WithDetail(err.Error()),
@@ -31,32 +96,134 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	uniqueLable := utils.GetUniqueLabel(req.Email, req.SSOID)
+	if req.RefreshToken == "" {
+		c.Error(errors.BadRequest(
+			errors.WithScope("AuthHandler"),
+			errors.WithLocation("RefreshToken.Validate"),
+			errors.WithMessage("refresh_token is required"),
+			errors.WithErrorCode("auth/missing-refresh-token"),
+		))
+		return
+	}
+
+	resp, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	accessToken := utils.ExtractBearerToken(c.GetHeader("Authorization"))
+	if accessToken == "" {
+		c.Error(errors.Unauthorized(
+			errors.WithScope("AuthHandler"),
+			errors.WithLocation("Logout.ExtractBearerToken"),
+			errors.WithMessage("missing bearer token"),
+			errors.WithErrorCode("auth/missing-token"),
+		))
+		return
+	}
+
+	resp, err := h.authService.Logout(c.Request.Context(), accessToken)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	accessToken := utils.ExtractBearerToken(c.GetHeader("Authorization"))
+	if accessToken == "" {
+		c.Error(errors.Unauthorized(
+			errors.WithScope("AuthHandler"),
+			errors.WithLocation("LogoutAll.ExtractBearerToken"),
+			errors.WithMessage("missing bearer token"),
+			errors.WithErrorCode("auth/missing-token"),
+		))
+		return
+	}
+
+	resp, err := h.authService.LogoutAll(c.Request.Context(), accessToken)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *AuthHandler) Me(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.Unauthorized(
+			errors.WithScope("AuthHandler"),
+			errors.WithLocation("Me.GetClaims"),
+			errors.WithMessage("unauthenticated"),
+			errors.WithErrorCode("auth/unauthenticated"),
+		))
+		return
+	}
+
+	c.JSON(200, claims)
+}
+
+// Introspect serves RFC 7662 token introspection to authenticated clients
+func (h *AuthHandler) Introspect(c *gin.Context) {
+	var req dto.IntrospectionRequest
+	if err := c.ShouldBind(&req); err != nil || req.Token == "" {
+		c.Error(errors.BadRequest(
+			errors.WithScope("AuthHandler"),
+			errors.WithLocation("Introspect.Bind"),
+			errors.WithMessage("token is required"),
+			errors.WithErrorCode("auth/invalid-request"),
+		))
+		return
+	}
+
+	resp, err := h.authService.Introspect(c.Request.Context(), req.Token)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(200, resp)
+}
+
+// describeUserAgent parses the request user agent into the JSON stored on a session
+func describeUserAgent(c *gin.Context, redisLabel string) (string, constant.DeviceClass) {
 	rawUserAgent := c.Request.UserAgent()
+	deviceInfo := utils.ParseUserAgent(rawUserAgent)
 	userAgent := dto.UserAgent{
-		Device:     utils.ParseUserAgent(rawUserAgent).Device,
-		Os:         utils.ParseUserAgent(rawUserAgent).OS,
-		Raw:        rawUserAgent,
-		RedisLabel: uniqueLable,
+		Device:      deviceInfo.Device,
+		Os:          deviceInfo.OS,
+		DeviceClass: deviceInfo.Class,
+		Raw:         rawUserAgent,
+		RedisLabel:  redisLabel,
 	}
 	formattedUserAgent, _ := json.Marshal(userAgent)
 
-	resp, err := h.authService.Login(
-		c.Request.Context(),
-		dto.LoginInput{
-			Email:         req.Email,
-			Password:      req.Password,
-			SSOID:         req.SSOID,
-			SSOPlatform:   req.SSOPlatform,
-			Device:        req.Device,
-			MacAddress:    req.MacAddress,
-			PublicKey:     req.PublicKey,
-			UserAgent:     string(formattedUserAgent),
-			IP:            c.ClientIP(),
-			Location:      c.GetHeader("X-Location"),
-			ClientVersion: c.GetHeader("x-client-version"),
-		},
-	)
+	return string(formattedUserAgent), deviceInfo.Class
+}
+
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var req dto.MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.MFAToken == "" || req.Code == "" {
+		c.Error(errors.BadRequest(
+			errors.WithScope("AuthHandler"),
+			errors.WithLocation("LoginMFA.BindJSON"),
+			errors.WithMessage("mfa_token and code are required"),
+			errors.WithErrorCode("auth/invalid-json"),
+		))
+		return
+	}
+
+	resp, err := h.authService.LoginMFA(c.Request.Context(), req)
 	if err != nil {
 		c.Error(err)
 		return
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/internal/middleware"
+	service "github.com/saifoelloh/ranger/internal/services"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type DeviceHandler struct {
+	deviceService *service.DeviceService
+}
+
+func NewDeviceHandler(deviceService *service.DeviceService) *DeviceHandler {
+	return &DeviceHandler{deviceService: deviceService}
+}
+
+func (h *DeviceHandler) LoginChallenge(c *gin.Context) {
+	var req dto.DeviceChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.SessionID == "" {
+		c.Error(errors.BadRequest(
+			errors.WithScope("DeviceHandler"),
+			errors.WithLocation("LoginChallenge.BindJSON"),
+			errors.WithMessage("session_id is required"),
+			errors.WithErrorCode("device/invalid-request"),
+		))
+		return
+	}
+
+	resp, err := h.deviceService.IssueLoginChallenge(c.Request.Context(), req.SessionID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *DeviceHandler) Login(c *gin.Context) {
+	var req dto.DeviceLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.SessionID == "" || req.Challenge == "" || req.Signature == "" {
+		c.Error(errors.BadRequest(
+			errors.WithScope("DeviceHandler"),
+			errors.WithLocation("Login.BindJSON"),
+			errors.WithMessage("session_id, challenge and signature are required"),
+			errors.WithErrorCode("device/invalid-request"),
+		))
+		return
+	}
+
+	formattedUserAgent, deviceClass := describeUserAgent(c, req.SessionID)
+	resp, err := h.deviceService.Login(c.Request.Context(), dto.DeviceLoginInput{
+		SessionID:     req.SessionID,
+		Challenge:     req.Challenge,
+		Signature:     req.Signature,
+		UserAgent:     formattedUserAgent,
+		IP:            c.ClientIP(),
+		Location:      c.GetHeader("X-Location"),
+		ClientVersion: c.GetHeader("x-client-version"),
+		DeviceClass:   deviceClass,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *DeviceHandler) StepUpChallenge(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.Unauthorized(
+			errors.WithScope("DeviceHandler"),
+			errors.WithLocation("StepUpChallenge.GetClaims"),
+			errors.WithMessage("unauthenticated"),
+			errors.WithErrorCode("auth/unauthenticated"),
+		))
+		return
+	}
+
+	resp, err := h.deviceService.IssueStepUpChallenge(c.Request.Context(), claims)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *DeviceHandler) StepUp(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.Unauthorized(
+			errors.WithScope("DeviceHandler"),
+			errors.WithLocation("StepUp.GetClaims"),
+			errors.WithMessage("unauthenticated"),
+			errors.WithErrorCode("auth/unauthenticated"),
+		))
+		return
+	}
+
+	var req dto.DeviceStepUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Challenge == "" || req.Signature == "" {
+		c.Error(errors.BadRequest(
+			errors.WithScope("DeviceHandler"),
+			errors.WithLocation("StepUp.BindJSON"),
+			errors.WithMessage("challenge and signature are required"),
+			errors.WithErrorCode("device/invalid-request"),
+		))
+		return
+	}
+
+	resp, err := h.deviceService.VerifyStepUp(c.Request.Context(), claims, req.Challenge, req.Signature)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
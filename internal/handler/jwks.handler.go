@@ -0,0 +1,19 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/saifoelloh/ranger/internal/jwk"
+)
+
+type JWKSHandler struct {
+	keyManager *jwk.KeyManager
+}
+
+func NewJWKSHandler(keyManager *jwk.KeyManager) *JWKSHandler {
+	return &JWKSHandler{keyManager: keyManager}
+}
+
+func (h *JWKSHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(200, h.keyManager.JWKS())
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/internal/middleware"
+	service "github.com/saifoelloh/ranger/internal/services"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type LockoutHandler struct {
+	lockoutService *service.LockoutService
+}
+
+func NewLockoutHandler(lockoutService *service.LockoutService) *LockoutHandler {
+	return &LockoutHandler{lockoutService: lockoutService}
+}
+
+func (h *LockoutHandler) Get(c *gin.Context) {
+	var query dto.LockoutQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(errors.BadRequest(
+			errors.WithScope("LockoutHandler"),
+			errors.WithLocation("Get.BindQuery"),
+			errors.WithMessage("invalid query parameters"),
+			errors.WithErrorCode("lockout/invalid-query"),
+			errors.WithDetail(err.Error()),
+		))
+		return
+	}
+
+	resp, err := h.lockoutService.Get(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *LockoutHandler) Clear(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.Unauthorized(
+			errors.WithScope("LockoutHandler"),
+			errors.WithLocation("Clear.GetClaims"),
+			errors.WithMessage("unauthenticated"),
+			errors.WithErrorCode("auth/unauthenticated"),
+		))
+		return
+	}
+
+	var query dto.LockoutQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(errors.BadRequest(
+			errors.WithScope("LockoutHandler"),
+			errors.WithLocation("Clear.BindQuery"),
+			errors.WithMessage("invalid query parameters"),
+			errors.WithErrorCode("lockout/invalid-query"),
+			errors.WithDetail(err.Error()),
+		))
+		return
+	}
+
+	resp, err := h.lockoutService.Clear(c.Request.Context(), claims, query, c.ClientIP())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
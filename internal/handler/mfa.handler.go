@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/internal/middleware"
+	service "github.com/saifoelloh/ranger/internal/services"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type MFAHandler struct {
+	mfaService *service.MFAService
+}
+
+func NewMFAHandler(mfaService *service.MFAService) *MFAHandler {
+	return &MFAHandler{mfaService: mfaService}
+}
+
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.Unauthorized(
+			errors.WithScope("MFAHandler"),
+			errors.WithLocation("Enroll.GetClaims"),
+			errors.WithMessage("unauthenticated"),
+			errors.WithErrorCode("auth/unauthenticated"),
+		))
+		return
+	}
+
+	// The code is only needed when replacing an active factor, so an empty body is fine
+	var req dto.MFACodeRequest
+	_ = c.ShouldBindJSON(&req)
+
+	resp, err := h.mfaService.Enroll(c.Request.Context(), claims, req.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *MFAHandler) Activate(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.Unauthorized(
+			errors.WithScope("MFAHandler"),
+			errors.WithLocation("Activate.GetClaims"),
+			errors.WithMessage("unauthenticated"),
+			errors.WithErrorCode("auth/unauthenticated"),
+		))
+		return
+	}
+
+	var req dto.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.Error(errors.BadRequest(
+			errors.WithScope("MFAHandler"),
+			errors.WithLocation("Activate.BindJSON"),
+			errors.WithMessage("code is required"),
+			errors.WithErrorCode("mfa/invalid-request"),
+		))
+		return
+	}
+
+	resp, err := h.mfaService.Activate(c.Request.Context(), claims, req.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *MFAHandler) Disable(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.Unauthorized(
+			errors.WithScope("MFAHandler"),
+			errors.WithLocation("Disable.GetClaims"),
+			errors.WithMessage("unauthenticated"),
+			errors.WithErrorCode("auth/unauthenticated"),
+		))
+		return
+	}
+
+	var req dto.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.Error(errors.BadRequest(
+			errors.WithScope("MFAHandler"),
+			errors.WithLocation("Disable.BindJSON"),
+			errors.WithMessage("code is required"),
+			errors.WithErrorCode("mfa/invalid-request"),
+		))
+		return
+	}
+
+	resp, err := h.mfaService.Disable(c.Request.Context(), claims, req.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/saifoelloh/ranger/internal/dto"
+	service "github.com/saifoelloh/ranger/internal/services"
+	"github.com/saifoelloh/ranger/internal/utils"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type OTPHandler struct {
+	otpService *service.OTPService
+}
+
+func NewOTPHandler(otpService *service.OTPService) *OTPHandler {
+	return &OTPHandler{otpService: otpService}
+}
+
+func (h *OTPHandler) Request(c *gin.Context) {
+	var req dto.OTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(
+			errors.WithScope("OTPHandler"),
+			errors.WithLocation("Request.BindJSON"),
+			errors.WithMessage("invalid request body"),
+			errors.WithErrorCode("otp/invalid-json"),
+			errors.WithDetail(err.Error()),
+		))
+		return
+	}
+
+	resp, err := h.otpService.RequestOTP(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *OTPHandler) Verify(c *gin.Context) {
+	var req dto.OTPVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(
+			errors.WithScope("OTPHandler"),
+			errors.WithLocation("Verify.BindJSON"),
+			errors.WithMessage("invalid request body"),
+			errors.WithErrorCode("otp/invalid-json"),
+			errors.WithDetail(err.Error()),
+		))
+		return
+	}
+
+	phoneHash := utils.CryptoHash(utils.NormalizePhoneNumber(req.PhoneNumber))
+	formattedUserAgent, deviceClass := describeUserAgent(c, phoneHash)
+
+	resp, err := h.otpService.VerifyOTP(c.Request.Context(), req, dto.LoginInput{
+		Device:        req.Device,
+		MacAddress:    req.MacAddress,
+		PublicKey:     req.PublicKey,
+		UserAgent:     formattedUserAgent,
+		IP:            c.ClientIP(),
+		Location:      c.GetHeader("X-Location"),
+		ClientVersion: c.GetHeader("x-client-version"),
+		DeviceClass:   deviceClass,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/internal/middleware"
+	service "github.com/saifoelloh/ranger/internal/services"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type PasswordHandler struct {
+	passwordService *service.PasswordService
+}
+
+func NewPasswordHandler(passwordService *service.PasswordService) *PasswordHandler {
+	return &PasswordHandler{passwordService: passwordService}
+}
+
+func (h *PasswordHandler) Forgot(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(
+			errors.WithScope("PasswordHandler"),
+			errors.WithLocation("Forgot.BindJSON"),
+			errors.WithMessage("invalid request body"),
+			errors.WithErrorCode("password/invalid-json"),
+			errors.WithDetail(err.Error()),
+		))
+		return
+	}
+
+	resp, err := h.passwordService.RequestReset(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *PasswordHandler) Reset(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(
+			errors.WithScope("PasswordHandler"),
+			errors.WithLocation("Reset.BindJSON"),
+			errors.WithMessage("invalid request body"),
+			errors.WithErrorCode("password/invalid-json"),
+			errors.WithDetail(err.Error()),
+		))
+		return
+	}
+
+	resp, err := h.passwordService.ConfirmReset(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *PasswordHandler) Change(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.Unauthorized(
+			errors.WithScope("PasswordHandler"),
+			errors.WithLocation("Change.GetClaims"),
+			errors.WithMessage("unauthenticated"),
+			errors.WithErrorCode("auth/unauthenticated"),
+		))
+		return
+	}
+
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(
+			errors.WithScope("PasswordHandler"),
+			errors.WithLocation("Change.BindJSON"),
+			errors.WithMessage("invalid request body"),
+			errors.WithErrorCode("password/invalid-json"),
+			errors.WithDetail(err.Error()),
+		))
+		return
+	}
+
+	resp, err := h.passwordService.ChangePassword(c.Request.Context(), claims, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/internal/middleware"
+	service "github.com/saifoelloh/ranger/internal/services"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type PinHandler struct {
+	pinService *service.PinService
+}
+
+func NewPinHandler(pinService *service.PinService) *PinHandler {
+	return &PinHandler{pinService: pinService}
+}
+
+func (h *PinHandler) Set(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.Unauthorized(
+			errors.WithScope("PinHandler"),
+			errors.WithLocation("Set.GetClaims"),
+			errors.WithMessage("unauthenticated"),
+			errors.WithErrorCode("auth/unauthenticated"),
+		))
+		return
+	}
+
+	var req dto.SetPinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(
+			errors.WithScope("PinHandler"),
+			errors.WithLocation("Set.BindJSON"),
+			errors.WithMessage("invalid request body"),
+			errors.WithErrorCode("pin/invalid-json"),
+			errors.WithDetail(err.Error()),
+		))
+		return
+	}
+
+	resp, err := h.pinService.SetPin(c.Request.Context(), claims, req.Pin)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *PinHandler) Change(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.Unauthorized(
+			errors.WithScope("PinHandler"),
+			errors.WithLocation("Change.GetClaims"),
+			errors.WithMessage("unauthenticated"),
+			errors.WithErrorCode("auth/unauthenticated"),
+		))
+		return
+	}
+
+	var req dto.ChangePinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(
+			errors.WithScope("PinHandler"),
+			errors.WithLocation("Change.BindJSON"),
+			errors.WithMessage("invalid request body"),
+			errors.WithErrorCode("pin/invalid-json"),
+			errors.WithDetail(err.Error()),
+		))
+		return
+	}
+
+	resp, err := h.pinService.ChangePin(c.Request.Context(), claims, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *PinHandler) Verify(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.Unauthorized(
+			errors.WithScope("PinHandler"),
+			errors.WithLocation("Verify.GetClaims"),
+			errors.WithMessage("unauthenticated"),
+			errors.WithErrorCode("auth/unauthenticated"),
+		))
+		return
+	}
+
+	var req dto.VerifyPinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(
+			errors.WithScope("PinHandler"),
+			errors.WithLocation("Verify.BindJSON"),
+			errors.WithMessage("invalid request body"),
+			errors.WithErrorCode("pin/invalid-json"),
+			errors.WithDetail(err.Error()),
+		))
+		return
+	}
+
+	resp, err := h.pinService.VerifyPin(c.Request.Context(), claims, req.Pin)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
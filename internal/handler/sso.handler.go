@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/saifoelloh/ranger/internal/constant"
+	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/internal/middleware"
+	service "github.com/saifoelloh/ranger/internal/services"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type SSOHandler struct {
+	ssoService *service.SSOService
+}
+
+func NewSSOHandler(ssoService *service.SSOService) *SSOHandler {
+	return &SSOHandler{ssoService: ssoService}
+}
+
+func (h *SSOHandler) Link(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.Unauthorized(
+			errors.WithScope("SSOHandler"),
+			errors.WithLocation("Link.GetClaims"),
+			errors.WithMessage("unauthenticated"),
+			errors.WithErrorCode("auth/unauthenticated"),
+		))
+		return
+	}
+
+	var req dto.LinkSSORequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(
+			errors.WithScope("SSOHandler"),
+			errors.WithLocation("Link.BindJSON"),
+			errors.WithMessage("invalid request body"),
+			errors.WithErrorCode("auth/invalid-json"),
+			errors.WithDetail(err.Error()),
+		))
+		return
+	}
+
+	resp, err := h.ssoService.LinkProvider(c.Request.Context(), claims, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *SSOHandler) Nonce(c *gin.Context) {
+	resp, err := h.ssoService.IssueNonce(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *SSOHandler) List(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.Unauthorized(
+			errors.WithScope("SSOHandler"),
+			errors.WithLocation("List.GetClaims"),
+			errors.WithMessage("unauthenticated"),
+			errors.WithErrorCode("auth/unauthenticated"),
+		))
+		return
+	}
+
+	resp, err := h.ssoService.ListProviders(c.Request.Context(), claims)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *SSOHandler) Unlink(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		c.Error(errors.Unauthorized(
+			errors.WithScope("SSOHandler"),
+			errors.WithLocation("Unlink.GetClaims"),
+			errors.WithMessage("unauthenticated"),
+			errors.WithErrorCode("auth/unauthenticated"),
+		))
+		return
+	}
+
+	platform := constant.SSOPlatform(strings.ToUpper(c.Param("platform")))
+	resp, err := h.ssoService.UnlinkProvider(c.Request.Context(), claims, platform)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/saifoelloh/ranger/internal/dto"
+	service "github.com/saifoelloh/ranger/internal/services"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type UserHandler struct {
+	userService *service.UserService
+}
+
+func NewUserHandler(userService *service.UserService) *UserHandler {
+	return &UserHandler{userService: userService}
+}
+
+func (h *UserHandler) Register(c *gin.Context) {
+	var req dto.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(
+			errors.WithScope("UserHandler"),
+			errors.WithLocation("Register.BindJSON"),
+			errors.WithMessage("invalid request body"),
+			errors.WithErrorCode("user/invalid-json"),
+			errors.WithDetail(err.Error()),
+		))
+		return
+	}
+
+	resp, err := h.userService.Register(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	var req dto.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(
+			errors.WithScope("UserHandler"),
+			errors.WithLocation("VerifyEmail.BindJSON"),
+			errors.WithMessage("invalid request body"),
+			errors.WithErrorCode("user/invalid-json"),
+			errors.WithDetail(err.Error()),
+		))
+		return
+	}
+
+	resp, err := h.userService.VerifyEmail(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
+
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	var req dto.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(
+			errors.WithScope("UserHandler"),
+			errors.WithLocation("ResendVerification.BindJSON"),
+			errors.WithMessage("invalid request body"),
+			errors.WithErrorCode("user/invalid-json"),
+			errors.WithDetail(err.Error()),
+		))
+		return
+	}
+
+	resp, err := h.userService.ResendVerificationCode(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(200, resp)
+}
@@ -0,0 +1,368 @@
+package jwk
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/saifoelloh/ranger/internal/config"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+// Key is a single verification key, optionally able to sign
+type Key struct {
+	ID          string
+	Method      jwt.SigningMethod
+	Public      crypto.PublicKey
+	Private     crypto.Signer // nil for verification-only keys
+	ActivatesAt time.Time     // private keys only start signing from this moment
+}
+
+// KeyManager holds the keys used to sign and verify tokens issued by this service.
+//
+// Keys are read from PEM files in a directory, one key per file named "<kid>.pem".
+// Private keys sign and verify, public keys only verify (e.g. retired keys). A private
+// key is activated by a "<kid>.json" file next to it, {"activates_at": "<RFC 3339>"},
+// so every instance and restart agrees on the moment. The active signing key is the
+// pinned kid if configured, otherwise the private key with the greatest kid whose
+// activation time has passed. Dropping a new key file into the directory therefore
+// publishes it in the JWKS immediately and makes it the signing key at its activation
+// time, giving verifiers time to pick it up. A private key without activation metadata
+// only signs when pinned or when no other key is active.
+//
+// Without a key directory the manager falls back to HS256 with the shared secret.
+type KeyManager struct {
+	dir         string
+	pinnedKeyID string
+	secret      []byte
+
+	mu      sync.RWMutex
+	keys    map[string]*Key
+	current *Key
+}
+
+func NewKeyManager(cfg config.Config) (*KeyManager, error) {
+	m := &KeyManager{
+		dir:         cfg.JwtKeysDir,
+		pinnedKeyID: cfg.JwtSigningKeyID,
+		secret:      []byte(cfg.JwtSecret),
+	}
+
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Asymmetric reports whether tokens are signed with a key pair rather than the shared secret
+func (m *KeyManager) Asymmetric() bool {
+	return m.dir != ""
+}
+
+// Reload re-reads the key directory and selects the signing key
+func (m *KeyManager) Reload() error {
+	if !m.Asymmetric() {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(m.dir, "*.pem"))
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("KeyManager"),
+			errors.WithLocation("Reload.Glob"),
+			errors.WithMessage("failed to list signing keys"),
+			errors.WithErrorCode("jwk/load-failed"),
+			errors.WithDetail(err.Error()),
+		)
+	}
+
+	keys := make(map[string]*Key, len(paths))
+	for _, path := range paths {
+		key, err := loadKey(path)
+		if err != nil {
+			return errors.InternalServerError(
+				errors.WithScope("KeyManager"),
+				errors.WithLocation("Reload.loadKey"),
+				errors.WithMessage("failed to load signing key"),
+				errors.WithErrorCode("jwk/load-failed"),
+				errors.WithDetail(err.Error()),
+			)
+		}
+		if key.Private != nil {
+			key.ActivatesAt, err = loadActivation(strings.TrimSuffix(path, filepath.Ext(path)) + ".json")
+			if err != nil {
+				return errors.InternalServerError(
+					errors.WithScope("KeyManager"),
+					errors.WithLocation("Reload.loadActivation"),
+					errors.WithMessage("failed to load signing key metadata"),
+					errors.WithErrorCode("jwk/load-failed"),
+					errors.WithDetail(err.Error()),
+				)
+			}
+		}
+		keys[key.ID] = key
+	}
+
+	current := m.selectSigningKey(keys)
+	if current == nil {
+		return errors.InternalServerError(
+			errors.WithScope("KeyManager"),
+			errors.WithLocation("Reload.selectSigningKey"),
+			errors.WithMessage("no usable signing key found"),
+			errors.WithErrorCode("jwk/no-signing-key"),
+			errors.WithDetail(m.dir),
+		)
+	}
+
+	m.mu.Lock()
+	previous := m.current
+	m.keys = keys
+	m.current = current
+	m.mu.Unlock()
+
+	if previous == nil || previous.ID != current.ID {
+		log.Printf("🔑 JWT signing key is now %q (%s)", current.ID, current.Method.Alg())
+	}
+
+	return nil
+}
+
+func (m *KeyManager) selectSigningKey(keys map[string]*Key) *Key {
+	if m.pinnedKeyID != "" {
+		if key, ok := keys[m.pinnedKeyID]; ok && key.Private != nil {
+			return key
+		}
+		return nil
+	}
+
+	ids := make([]string, 0, len(keys))
+	for id, key := range keys {
+		if key.Private != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+
+	now := time.Now()
+	for _, id := range ids {
+		if activatesAt := keys[id].ActivatesAt; !activatesAt.IsZero() && !activatesAt.After(now) {
+			return keys[id]
+		}
+	}
+
+	// Nothing has activated yet (e.g. first boot): sign with the oldest key rather than fail
+	if len(ids) > 0 {
+		return keys[ids[len(ids)-1]]
+	}
+	return nil
+}
+
+// StartRotation reloads the key directory on every tick until ctx is done
+func (m *KeyManager) StartRotation(ctx context.Context, interval time.Duration) {
+	if !m.Asymmetric() || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Reload(); err != nil {
+				log.Printf("[ERROR] KeyManager/StartRotation - %s", err.Error())
+			}
+		}
+	}
+}
+
+// Sign signs the claims with the current key, setting the kid and typ headers
+func (m *KeyManager) Sign(claims jwt.Claims, typ string) (string, error) {
+	if !m.Asymmetric() {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		token.Header["typ"] = typ
+		return token.SignedString(m.secret)
+	}
+
+	m.mu.RLock()
+	key := m.current
+	m.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	token.Header["typ"] = typ
+	return token.SignedString(key.Private)
+}
+
+// Keyfunc resolves the verification key of a token from its kid header
+func (m *KeyManager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if !m.Asymmetric() {
+		return m.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	m.mu.RLock()
+	key, ok := m.keys[kid]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("signing method %s does not match key %q", token.Method.Alg(), kid)
+	}
+
+	return key.Public, nil
+}
+
+// ValidMethods lists the algorithms tokens may be signed with
+func (m *KeyManager) ValidMethods() []string {
+	if !m.Asymmetric() {
+		return []string{jwt.SigningMethodHS256.Alg()}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := map[string]bool{}
+	methods := []string{}
+	for _, key := range m.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// JWKS returns the public part of every loaded key
+func (m *KeyManager) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	if !m.Asymmetric() {
+		return set
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]string, 0, len(m.keys))
+	for id := range m.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		key := m.keys[id]
+		set.Keys = append(set.Keys, NewJSONWebKey(key.ID, key.Method.Alg(), key.Public))
+	}
+	return set
+}
+
+func loadKey(path string) (*Key, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", path)
+	}
+
+	kid := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	key := &Key{ID: kid}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key.Public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key.Public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key.Private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key.Private, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		var parsed interface{}
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err == nil {
+			signer, ok := parsed.(crypto.Signer)
+			if !ok {
+				return nil, fmt.Errorf("%s: unsupported private key", path)
+			}
+			key.Private = signer
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if key.Private != nil {
+		key.Public = key.Private.Public()
+	}
+
+	key.Method, err = signingMethodFor(key.Public)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return key, nil
+}
+
+// keyMetadata is the "<kid>.json" file next to a private key
+type keyMetadata struct {
+	ActivatesAt time.Time `json:"activates_at"`
+}
+
+// loadActivation reads the activation time of a private key, zero when it has no metadata file
+func loadActivation(path string) (time.Time, error) {
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var metadata keyMetadata
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return metadata.ActivatesAt, nil
+}
+
+func signingMethodFor(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		}
+		return nil, fmt.Errorf("unsupported curve %s", k.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", public)
+}
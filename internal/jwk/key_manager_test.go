@@ -0,0 +1,162 @@
+package jwk
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+func TestSelectSigningKey(t *testing.T) {
+	now := time.Now()
+	signing := func(id string, activatesAt time.Time) *Key {
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, Public: public, Private: private, ActivatesAt: activatesAt}
+	}
+	verifying := func(id string) *Key {
+		public, _, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, Public: public}
+	}
+	keySet := func(keys ...*Key) map[string]*Key {
+		set := make(map[string]*Key, len(keys))
+		for _, key := range keys {
+			set[key.ID] = key
+		}
+		return set
+	}
+
+	tests := []struct {
+		name   string
+		pinned string
+		keys   map[string]*Key
+		want   string // empty when no key may sign
+	}{
+		{
+			name: "greatest activated kid",
+			keys: keySet(signing("2024-01", now.Add(-48*time.Hour)), signing("2024-02", now.Add(-time.Hour))),
+			want: "2024-02",
+		},
+		{
+			name: "newer key not activated yet",
+			keys: keySet(signing("2024-01", now.Add(-48*time.Hour)), signing("2024-02", now.Add(time.Hour))),
+			want: "2024-01",
+		},
+		{
+			name: "nothing activated falls back to the oldest key",
+			keys: keySet(signing("2024-01", now.Add(time.Hour)), signing("2024-02", now.Add(2*time.Hour))),
+			want: "2024-01",
+		},
+		{
+			name: "key without activation metadata",
+			keys: keySet(signing("2024-01", now.Add(-48*time.Hour)), signing("2024-02", time.Time{})),
+			want: "2024-01",
+		},
+		{
+			name: "public keys never sign",
+			keys: keySet(signing("2024-01", now.Add(-48*time.Hour)), verifying("2024-02")),
+			want: "2024-01",
+		},
+		{
+			name: "only public keys",
+			keys: keySet(verifying("2024-01")),
+		},
+		{
+			name: "no keys",
+			keys: keySet(),
+		},
+		{
+			name:   "pinned key wins even before activation",
+			pinned: "2024-01",
+			keys:   keySet(signing("2024-01", now.Add(time.Hour)), signing("2024-02", now.Add(-time.Hour))),
+			want:   "2024-01",
+		},
+		{
+			name:   "pinned key missing",
+			pinned: "2023-12",
+			keys:   keySet(signing("2024-01", now.Add(-time.Hour))),
+		},
+		{
+			name:   "pinned key without private part",
+			pinned: "2024-01",
+			keys:   keySet(verifying("2024-01"), signing("2024-02", now.Add(-time.Hour))),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &KeyManager{pinnedKeyID: tt.pinned}
+			got := m.selectSigningKey(tt.keys)
+
+			switch {
+			case got == nil && tt.want != "":
+				t.Errorf("selectSigningKey() = nil, want %q", tt.want)
+			case got != nil && got.ID != tt.want:
+				t.Errorf("selectSigningKey() = %q, want %q", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestReloadActivation(t *testing.T) {
+	dir := t.TempDir()
+	writeKey := func(id string) {
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err := os.WriteFile(filepath.Join(dir, id+".pem"), pemBytes, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeMetadata := func(id, content string) {
+		if err := os.WriteFile(filepath.Join(dir, id+".json"), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The files are written now, the activation times come from the metadata only
+	writeKey("2024-01")
+	writeMetadata("2024-01", `{"activates_at": "2024-01-01T00:00:00Z"}`)
+	writeKey("2024-02")
+	writeMetadata("2024-02", `{"activates_at": "2999-01-01T00:00:00Z"}`)
+	writeKey("2024-03")
+
+	m := &KeyManager{dir: dir}
+	if err := m.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if m.current.ID != "2024-01" {
+		t.Errorf("signing key = %q, want %q", m.current.ID, "2024-01")
+	}
+	if want := time.Date(2999, 1, 1, 0, 0, 0, 0, time.UTC); !m.keys["2024-02"].ActivatesAt.Equal(want) {
+		t.Errorf("ActivatesAt = %v, want %v", m.keys["2024-02"].ActivatesAt, want)
+	}
+	if !m.keys["2024-03"].ActivatesAt.IsZero() {
+		t.Errorf("ActivatesAt without metadata = %v, want zero", m.keys["2024-03"].ActivatesAt)
+	}
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		writeMetadata("2024-03", "not json")
+		if err := m.Reload(); err == nil {
+			t.Error("Reload() error = nil, want an error")
+		}
+	})
+}
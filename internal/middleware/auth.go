@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/saifoelloh/ranger/internal/dto"
+	service "github.com/saifoelloh/ranger/internal/services"
+	"github.com/saifoelloh/ranger/internal/utils"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+// ClaimsKey is the gin context key holding the *dto.AppClaims of an authenticated request
+const ClaimsKey = "claims"
+
+// Authenticate rejects requests without a valid bearer access token and stores its claims in the context
+func Authenticate(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accessToken := utils.ExtractBearerToken(c.GetHeader("Authorization"))
+		if accessToken == "" {
+			c.Error(errors.Unauthorized(
+				errors.WithScope("AuthMiddleware"),
+				errors.WithLocation("Authenticate.ExtractBearerToken"),
+				errors.WithMessage("missing bearer token"),
+				errors.WithErrorCode("auth/missing-token"),
+			))
+			c.Abort()
+			return
+		}
+
+		claims, err := authService.ValidateAccessToken(c.Request.Context(), accessToken)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		c.Set(ClaimsKey, claims)
+		c.Next()
+	}
+}
+
+// AuthenticateMFAEnrollment is Authenticate for the MFA enrollment token handed to admins who
+// have not enrolled a second factor yet
+func AuthenticateMFAEnrollment(authService *service.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := utils.ExtractBearerToken(c.GetHeader("Authorization"))
+		if token == "" {
+			c.Error(errors.Unauthorized(
+				errors.WithScope("AuthMiddleware"),
+				errors.WithLocation("AuthenticateMFAEnrollment.ExtractBearerToken"),
+				errors.WithMessage("missing bearer token"),
+				errors.WithErrorCode("auth/missing-token"),
+			))
+			c.Abort()
+			return
+		}
+
+		claims, err := authService.ValidateMFAEnrollmentToken(token, c.ClientIP())
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		c.Set(ClaimsKey, claims)
+		c.Next()
+	}
+}
+
+// GetClaims returns the claims stored by Authenticate or AuthenticateMFAEnrollment
+func GetClaims(c *gin.Context) (*dto.AppClaims, bool) {
+	value, ok := c.Get(ClaimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*dto.AppClaims)
+	return claims, ok
+}
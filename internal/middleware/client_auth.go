@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/gin-gonic/gin"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+// ClientIDKey is the gin context key holding the id of a client authenticated by AuthenticateClient
+const ClientIDKey = "client_id"
+
+// AuthenticateClient rejects requests without HTTP Basic credentials of one of the configured clients
+func AuthenticateClient(clients map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, secret, ok := c.Request.BasicAuth()
+		expected, known := clients[id]
+		if !ok || !known || subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) != 1 {
+			c.Header("WWW-Authenticate", `Basic realm="ranger"`)
+			c.Error(errors.Unauthorized(
+				errors.WithScope("ClientAuthMiddleware"),
+				errors.WithLocation("AuthenticateClient.BasicAuth"),
+				errors.WithMessage("invalid client credentials"),
+				errors.WithErrorCode("auth/invalid-client"),
+			))
+			c.Abort()
+			return
+		}
+
+		c.Set(ClientIDKey, id)
+		c.Next()
+	}
+}
@@ -0,0 +1,11 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// TrustProxies makes c.ClientIP() read the forwarding headers only from the given proxies, or
+// the header of the given platform. gin trusts every proxy by default, which would let clients
+// pick their own address with X-Forwarded-For.
+func TrustProxies(router *gin.Engine, proxies []string, platform string) error {
+	router.TrustedPlatform = platform
+	return router.SetTrustedProxies(proxies)
+}
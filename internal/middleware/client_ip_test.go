@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTrustProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		proxies    []string
+		platform   string
+		remoteAddr string
+		headers    map[string]string
+		wantIP     string
+	}{
+		{
+			name:       "spoofed header without trusted proxies",
+			proxies:    []string{},
+			remoteAddr: "198.51.100.7:40000",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.1", "X-Real-IP": "203.0.113.2"},
+			wantIP:     "198.51.100.7",
+		},
+		{
+			name:       "spoofed header from an untrusted address",
+			proxies:    []string{"10.0.0.0/8"},
+			remoteAddr: "198.51.100.7:40000",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.1"},
+			wantIP:     "198.51.100.7",
+		},
+		{
+			name:       "forwarded by a trusted proxy",
+			proxies:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.1.2.3:40000",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.1"},
+			wantIP:     "203.0.113.1",
+		},
+		{
+			name:       "client prepends a spoofed hop before the trusted proxy",
+			proxies:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.1.2.3:40000",
+			headers:    map[string]string{"X-Forwarded-For": "192.0.2.99, 203.0.113.1"},
+			wantIP:     "203.0.113.1",
+		},
+		{
+			name:       "spoofed platform header when no platform is trusted",
+			proxies:    []string{},
+			remoteAddr: "198.51.100.7:40000",
+			headers:    map[string]string{gin.PlatformCloudflare: "203.0.113.1"},
+			wantIP:     "198.51.100.7",
+		},
+		{
+			name:       "trusted platform header",
+			proxies:    []string{},
+			platform:   gin.PlatformCloudflare,
+			remoteAddr: "198.51.100.7:40000",
+			headers:    map[string]string{gin.PlatformCloudflare: "203.0.113.1"},
+			wantIP:     "203.0.113.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			if err := TrustProxies(router, tt.proxies, tt.platform); err != nil {
+				t.Fatal(err)
+			}
+			var gotIP string
+			router.GET("/", func(c *gin.Context) { gotIP = c.ClientIP() })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			// ClientIP is what the login rate limiter counts on
+			if gotIP != tt.wantIP {
+				t.Errorf("ClientIP() = %q, want %q", gotIP, tt.wantIP)
+			}
+		})
+	}
+
+	t.Run("invalid proxy", func(t *testing.T) {
+		if err := TrustProxies(gin.New(), []string{"not-an-ip"}, ""); err == nil {
+			t.Error("TrustProxies() error = nil, want an error")
+		}
+	})
+}
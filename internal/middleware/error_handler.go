@@ -20,6 +20,10 @@ func ErrorHandler() gin.HandlerFunc {
 					fmt.Printf("Detail: %+v\n", extErr.Detail)
 				}
 
+				for key, value := range extErr.Extra {
+					c.Header(key, value)
+				}
+
 				// Send structured error to client
 				c.AbortWithStatusJSON(extErr.StatusCode, gin.H{
 					"error": gin.H{
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/saifoelloh/ranger/internal/ratelimit"
+	"github.com/saifoelloh/ranger/internal/redis"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+// RateLimitAPI counts every request of the authenticated user against the quota of its route and
+// role, rejecting it once the quota is used up. It must run after Authenticate.
+func RateLimitAPI(limiter *redis.RateLimiterRepository, policy ratelimit.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		route := c.Request.Method + " " + c.FullPath()
+		takeQuota(c, limiter, claims.UserID, route, policy.QuotaFor(route, claims.Role))
+	}
+}
+
+// RateLimitClientIP counts every request from the client address against the quota of its route,
+// for public routes that have no user to count on
+func RateLimitClientIP(limiter *redis.RateLimiterRepository, policy ratelimit.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		takeQuota(c, limiter, "ip:"+c.ClientIP(), route, policy.QuotaFor(route, ""))
+	}
+}
+
+// takeQuota counts the request of subject and sets the rate limit headers, aborting once the quota is used up
+func takeQuota(c *gin.Context, limiter *redis.RateLimiterRepository, subject, route string, quota ratelimit.Quota) {
+	result, err := limiter.TakeAPIQuota(c.Request.Context(), subject, route, quota)
+	if err != nil {
+		c.Error(err)
+		c.Abort()
+		return
+	}
+	if result == nil {
+		c.Next()
+		return
+	}
+
+	reset := strconv.FormatInt(time.Now().Add(result.Reset).Unix(), 10)
+	c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("X-RateLimit-Reset", reset)
+
+	if !result.Allowed {
+		c.Error(errors.TooManyRequests(
+			errors.WithScope("RateLimitMiddleware"),
+			errors.WithLocation("RateLimitAPI"),
+			errors.WithMessage("rate limit exceeded. try again later"),
+			errors.WithErrorCode("api/rate-limited"),
+			errors.WithExtra("Retry-After", strconv.FormatInt(int64(math.Ceil(result.RetryAfter.Seconds())), 10)),
+		))
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
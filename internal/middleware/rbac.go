@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/saifoelloh/ranger/internal/rbac"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+// RequirePermission rejects authenticated requests whose token lacks any of the permissions.
+// It must run after Authenticate.
+func RequirePermission(permissions ...rbac.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			c.Error(errors.Unauthorized(
+				errors.WithScope("RBACMiddleware"),
+				errors.WithLocation("RequirePermission.GetClaims"),
+				errors.WithMessage("unauthenticated"),
+				errors.WithErrorCode("auth/unauthenticated"),
+			))
+			c.Abort()
+			return
+		}
+
+		for _, permission := range permissions {
+			if !rbac.Allows(claims.Permissions, permission) {
+				c.Error(errors.Forbidden(
+					errors.WithScope("RBACMiddleware"),
+					errors.WithLocation("RequirePermission"),
+					errors.WithMessage("missing permission "+string(permission)),
+					errors.WithErrorCode("auth/forbidden"),
+				))
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+type AuditLog struct {
+	ID         string         `db:"id"`
+	ActorID    string         `db:"actor_id"`
+	ActorRole  string         `db:"actor_role"`
+	Action     string         `db:"action"`
+	TargetType string         `db:"target_type"`
+	TargetID   string         `db:"target_id"`
+	Reason     sql.NullString `db:"reason"`
+	Detail     string         `db:"detail"` // JSON describing what the action changed
+	IP         string         `db:"ip"`
+	CreatedAt  time.Time      `db:"createdAt"`
+}
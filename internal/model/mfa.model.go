@@ -0,0 +1,24 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+type UserTotp struct {
+	UserID        string         `db:"user_id"`
+	Secret        string         `db:"secret"`         // encrypted with utils.Encrypt
+	PendingSecret sql.NullString `db:"pending_secret"` // enrolled but not yet activated, encrypted like Secret
+	Enabled       bool           `db:"enabled"`
+	LastUsedStep  int64          `db:"last_used_step"`
+	CreatedAt     time.Time      `db:"createdAt"`
+	UpdatedAt     time.Time      `db:"updatedAt"`
+}
+
+type RecoveryCode struct {
+	ID        string       `db:"id"`
+	UserID    string       `db:"user_id"`
+	CodeHash  string       `db:"code_hash"`
+	UsedAt    sql.NullTime `db:"used_at"`
+	CreatedAt time.Time    `db:"createdAt"`
+}
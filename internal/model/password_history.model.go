@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+type PasswordHistory struct {
+	ID        string    `db:"id"`
+	UserID    string    `db:"user_id"`
+	Password  string    `db:"password"` // bcrypt hash of a password the user no longer uses
+	CreatedAt time.Time `db:"createdAt"`
+}
@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+type UserPin struct {
+	UserID    string    `db:"user_id"`
+	Pin       string    `db:"pin"` // bcrypt hash
+	CreatedAt time.Time `db:"createdAt"`
+	UpdatedAt time.Time `db:"updatedAt"`
+}
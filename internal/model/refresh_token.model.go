@@ -0,0 +1,20 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+type RefreshToken struct {
+	ID        string         `db:"id"`
+	SessionID string         `db:"session_id"`
+	UserID    string         `db:"user_id"`
+	FamilyID  string         `db:"family_id"`
+	ParentID  sql.NullString `db:"parent_id"`
+	TokenHash string         `db:"token_hash"`
+	Revoked   bool           `db:"revoked"`
+	UsedAt    sql.NullTime   `db:"used_at"`
+	ExpiresAt time.Time      `db:"expires_at"`
+	CreatedAt time.Time      `db:"createdAt"`
+	UpdatedAt time.Time      `db:"updatedAt"`
+}
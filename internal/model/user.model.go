@@ -12,6 +12,7 @@ type User struct {
 	Email                 sql.NullString `db:"email"`
 	EmailHash             sql.NullString `db:"email_hash"`
 	EmailVerificationCode string         `db:"email_verification_code"`
+	EmailCodeExpiresAt    sql.NullTime   `db:"email_verification_expires_at"`
 	EmailVerified         bool           `db:"email_verified"`
 	Password              sql.NullString `db:"password"`
 	PhoneNumber           sql.NullString `db:"phone_number"`
@@ -0,0 +1,101 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/saifoelloh/ranger/internal/config"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type Channel string
+
+const (
+	ChannelSMS      Channel = "SMS"
+	ChannelWhatsApp Channel = "WHATSAPP"
+	ChannelEmail    Channel = "EMAIL"
+)
+
+type Message struct {
+	Channel Channel `json:"channel"`
+	To      string  `json:"to"`
+	Subject string  `json:"subject,omitempty"`
+	Body    string  `json:"body"`
+}
+
+// Sender delivers a message to a user. Production gateways implement this interface;
+// LogSender and FileSender are stand-ins for local development.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender returns the sender selected by NOTIFIER_DRIVER
+func NewSender(cfg config.Config) (Sender, error) {
+	switch cfg.NotifierDriver {
+	case "", "log":
+		return LogSender{}, nil
+	case "file":
+		return &FileSender{path: cfg.NotifierFilePath}, nil
+	}
+
+	return nil, errors.InternalServerError(
+		errors.WithScope("Notifier"),
+		errors.WithLocation("NewSender"),
+		errors.WithMessage("unknown notifier driver"),
+		errors.WithErrorCode("notifier/unknown-driver"),
+		errors.WithDetail(cfg.NotifierDriver),
+	)
+}
+
+// LogSender writes messages to the application log
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("📨 [%s] to=%s subject=%q body=%q", msg.Channel, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileSender appends messages as JSON lines to a file, handy for automated tests
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	line, err := json.Marshal(struct {
+		Message
+		SentAt time.Time `json:"sent_at"`
+	}{msg, time.Now()})
+	if err != nil {
+		return s.fail("Send.Marshal", err)
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return s.fail("Send.OpenFile", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return s.fail("Send.Write", err)
+	}
+
+	return nil
+}
+
+func (s *FileSender) fail(location string, err error) error {
+	return errors.InternalServerError(
+		errors.WithScope("FileSender"),
+		errors.WithLocation(location),
+		errors.WithMessage("failed to deliver message"),
+		errors.WithErrorCode("notifier/send-failed"),
+		errors.WithDetail(err.Error()),
+	)
+}
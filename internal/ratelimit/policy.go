@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Algorithm is how a quota counts requests
+type Algorithm string
+
+const (
+	// TokenBucket refills Limit tokens per Window and holds up to Limit+Burst tokens
+	TokenBucket Algorithm = "token_bucket"
+	// SlidingWindowLog allows at most Limit requests in any Window, Burst does not apply
+	SlidingWindowLog Algorithm = "sliding_window_log"
+)
+
+// Quota is the request allowance of one user on one route
+type Quota struct {
+	Algorithm Algorithm
+	Limit     int
+	Window    time.Duration
+	Burst     int
+}
+
+func (q *Quota) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Algorithm Algorithm `json:"algorithm"`
+		Limit     int       `json:"limit"`
+		Window    string    `json:"window"`
+		Burst     int       `json:"burst"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	window, err := time.ParseDuration(raw.Window)
+	if err != nil {
+		return fmt.Errorf("invalid window %q: %w", raw.Window, err)
+	}
+	if raw.Algorithm == "" {
+		raw.Algorithm = TokenBucket
+	}
+	if raw.Algorithm != TokenBucket && raw.Algorithm != SlidingWindowLog {
+		return fmt.Errorf("unknown algorithm %q", raw.Algorithm)
+	}
+	if raw.Limit < 1 || window <= 0 || raw.Burst < 0 {
+		return fmt.Errorf("limit and window must be positive and burst not negative")
+	}
+
+	*q = Quota{Algorithm: raw.Algorithm, Limit: raw.Limit, Window: window, Burst: raw.Burst}
+	return nil
+}
+
+// RouteQuota overrides the quota of a single route, optionally per role
+type RouteQuota struct {
+	Default *Quota           `json:"default"`
+	Roles   map[string]Quota `json:"roles"`
+}
+
+// Policy declares the quotas applied by the API rate limiter. The most specific quota wins:
+// route and role, then route, then role, then the default.
+type Policy struct {
+	Default Quota                 `json:"default"`
+	Roles   map[string]Quota      `json:"roles"`
+	Routes  map[string]RouteQuota `json:"routes"` // keyed by "<METHOD> <route path>", e.g. "GET /sessions/:id"
+}
+
+// DefaultPolicy is used when no rate limit policy file is configured
+func DefaultPolicy() Policy {
+	return Policy{
+		Default: Quota{Algorithm: TokenBucket, Limit: 120, Window: time.Minute, Burst: 30},
+		Routes: map[string]RouteQuota{
+			"POST /device/challenge": {Default: &Quota{Algorithm: SlidingWindowLog, Limit: 10, Window: time.Minute}},
+			"POST /password/change":  {Default: &Quota{Algorithm: SlidingWindowLog, Limit: 5, Window: time.Hour}},
+			"POST /pin/verify":       {Default: &Quota{Algorithm: SlidingWindowLog, Limit: 10, Window: time.Minute}},
+			"POST /register":         {Default: &Quota{Algorithm: SlidingWindowLog, Limit: 10, Window: time.Hour}},
+			"POST /sso/link":         {Default: &Quota{Algorithm: SlidingWindowLog, Limit: 10, Window: time.Hour}},
+		},
+	}
+}
+
+// LoadPolicy reads a JSON policy file. An empty path returns the default policy.
+func LoadPolicy(path string) (Policy, error) {
+	if path == "" {
+		return DefaultPolicy(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Policy{}, fmt.Errorf("read rate limit policy %s: %w", path, err)
+	}
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return Policy{}, fmt.Errorf("parse rate limit policy %s: %w", path, err)
+	}
+	if policy.Default.Limit == 0 {
+		return Policy{}, fmt.Errorf("rate limit policy %s: missing default quota", path)
+	}
+	return policy, nil
+}
+
+// QuotaFor returns the quota applied to a role calling route
+func (p Policy) QuotaFor(route, role string) Quota {
+	if override, ok := p.Routes[route]; ok {
+		if quota, ok := override.Roles[role]; ok {
+			return quota
+		}
+		if override.Default != nil {
+			return *override.Default
+		}
+	}
+	if quota, ok := p.Roles[role]; ok {
+		return quota
+	}
+	return p.Default
+}
@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestQuotaUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    Quota
+		wantErr bool
+	}{
+		{
+			name: "token bucket",
+			json: `{"algorithm": "token_bucket", "limit": 60, "window": "1m", "burst": 10}`,
+			want: Quota{Algorithm: TokenBucket, Limit: 60, Window: time.Minute, Burst: 10},
+		},
+		{
+			name: "sliding window log",
+			json: `{"algorithm": "sliding_window_log", "limit": 5, "window": "1h"}`,
+			want: Quota{Algorithm: SlidingWindowLog, Limit: 5, Window: time.Hour},
+		},
+		{
+			name: "algorithm defaults to token bucket",
+			json: `{"limit": 1, "window": "1s"}`,
+			want: Quota{Algorithm: TokenBucket, Limit: 1, Window: time.Second},
+		},
+		{name: "unknown algorithm", json: `{"algorithm": "leaky_bucket", "limit": 1, "window": "1s"}`, wantErr: true},
+		{name: "invalid window", json: `{"limit": 1, "window": "soon"}`, wantErr: true},
+		{name: "missing window", json: `{"limit": 1}`, wantErr: true},
+		{name: "zero limit", json: `{"limit": 0, "window": "1s"}`, wantErr: true},
+		{name: "zero window", json: `{"limit": 1, "window": "0s"}`, wantErr: true},
+		{name: "negative burst", json: `{"limit": 1, "window": "1s", "burst": -1}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Quota
+			err := json.Unmarshal([]byte(tt.json), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Unmarshal() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyQuotaFor(t *testing.T) {
+	base := Quota{Algorithm: TokenBucket, Limit: 100, Window: time.Minute}
+	admin := Quota{Algorithm: TokenBucket, Limit: 1000, Window: time.Minute}
+	route := Quota{Algorithm: SlidingWindowLog, Limit: 5, Window: time.Hour}
+	routeAdmin := Quota{Algorithm: SlidingWindowLog, Limit: 50, Window: time.Hour}
+
+	policy := Policy{
+		Default: base,
+		Roles:   map[string]Quota{"ADMIN": admin},
+		Routes: map[string]RouteQuota{
+			"POST /password/change": {Default: &route, Roles: map[string]Quota{"ADMIN": routeAdmin}},
+			"POST /pin/verify":      {Roles: map[string]Quota{"INVESTOR": route}},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		route string
+		role  string
+		want  Quota
+	}{
+		{name: "route and role", route: "POST /password/change", role: "ADMIN", want: routeAdmin},
+		{name: "route default", route: "POST /password/change", role: "INVESTOR", want: route},
+		{name: "role without route override", route: "GET /me", role: "ADMIN", want: admin},
+		{name: "default", route: "GET /me", role: "INVESTOR", want: base},
+		{name: "route with only other roles falls back to role", route: "POST /pin/verify", role: "ADMIN", want: admin},
+		{name: "route with only other roles falls back to default", route: "POST /pin/verify", role: "SUPPORT", want: base},
+		{name: "route role without route default", route: "POST /pin/verify", role: "INVESTOR", want: route},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.QuotaFor(tt.route, tt.role); got != tt.want {
+				t.Errorf("QuotaFor(%q, %q) = %+v, want %+v", tt.route, tt.role, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			content: `{"default": {"limit": 60, "window": "1m"}, "routes": {"GET /me": {"default": {"limit": 5, "window": "1s"}}}}`,
+		},
+		{name: "missing default", content: `{"roles": {"ADMIN": {"limit": 60, "window": "1m"}}}`, wantErr: true},
+		{name: "invalid quota", content: `{"default": {"limit": 60, "window": "forever"}}`, wantErr: true},
+		{name: "not json", content: `limit: 60`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := LoadPolicy(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("empty path uses the default policy", func(t *testing.T) {
+		policy, err := LoadPolicy("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if policy.Default != DefaultPolicy().Default {
+			t.Errorf("LoadPolicy(\"\").Default = %+v, want %+v", policy.Default, DefaultPolicy().Default)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := LoadPolicy(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+			t.Error("LoadPolicy() error = nil, want an error")
+		}
+	})
+}
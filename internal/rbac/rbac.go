@@ -0,0 +1,105 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/saifoelloh/ranger/internal/constant"
+)
+
+// Permission is an action a role may perform, written as "<resource>:<action>"
+type Permission string
+
+// Wildcard grants every permission
+const Wildcard Permission = "*"
+
+const (
+	PermissionProfileRead      Permission = "profile:read"
+	PermissionSessionsManage   Permission = "sessions:manage"
+	PermissionTransactionWrite Permission = "transactions:write"
+	PermissionPortfolioRead    Permission = "portfolio:read"
+
+	PermissionUsersRead      Permission = "users:read"
+	PermissionUsersWrite     Permission = "users:write"
+	PermissionUserSessions   Permission = "users:sessions"
+	PermissionLockoutsRead   Permission = "lockouts:read"
+	PermissionLockoutsManage Permission = "lockouts:manage"
+	PermissionAuditRead      Permission = "audit:read"
+	PermissionFinanceRead    Permission = "finance:read"
+	PermissionFinanceWrite   Permission = "finance:write"
+	PermissionReportsRead    Permission = "reports:read"
+	PermissionCampaignsWrite Permission = "campaigns:write"
+	PermissionProjectsWrite  Permission = "projects:write"
+)
+
+// Matrix maps every role to the permissions it is granted
+type Matrix map[constant.UserRole][]Permission
+
+// DefaultMatrix is used when no permission matrix file is configured
+func DefaultMatrix() Matrix {
+	return Matrix{
+		constant.RoleSuperadmin: {Wildcard},
+		constant.RoleOperations: {
+			PermissionUsersRead, PermissionUsersWrite, PermissionUserSessions,
+			PermissionLockoutsRead, PermissionLockoutsManage, PermissionProjectsWrite, PermissionReportsRead,
+		},
+		constant.RoleFinance: {
+			PermissionUsersRead, PermissionFinanceRead, PermissionFinanceWrite, PermissionReportsRead,
+		},
+		constant.RoleBusiness: {
+			PermissionUsersRead, PermissionProjectsWrite, PermissionReportsRead,
+		},
+		constant.RoleMarketing: {
+			PermissionCampaignsWrite, PermissionReportsRead,
+		},
+		constant.RoleAuditor: {
+			PermissionUsersRead, PermissionLockoutsRead, PermissionAuditRead, PermissionFinanceRead, PermissionReportsRead,
+		},
+		constant.RoleSupport: {
+			PermissionUsersRead, PermissionUserSessions, PermissionLockoutsRead, PermissionLockoutsManage,
+		},
+		constant.RoleInvestor: {
+			PermissionProfileRead, PermissionSessionsManage, PermissionTransactionWrite, PermissionPortfolioRead,
+		},
+	}
+}
+
+// LoadMatrix reads a JSON file of {"<ROLE>": ["<permission>", ...]}. An empty path returns the default matrix.
+func LoadMatrix(path string) (Matrix, error) {
+	if path == "" {
+		return DefaultMatrix(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read permission matrix %s: %w", path, err)
+	}
+	var matrix Matrix
+	if err := json.Unmarshal(data, &matrix); err != nil {
+		return nil, fmt.Errorf("parse permission matrix %s: %w", path, err)
+	}
+	return matrix, nil
+}
+
+// PermissionsFor returns the sorted permissions of a role, nil for unknown roles
+func (m Matrix) PermissionsFor(role string) []string {
+	granted := m[constant.UserRole(role)]
+	if len(granted) == 0 {
+		return nil
+	}
+
+	permissions := make([]string, 0, len(granted))
+	for _, p := range granted {
+		permissions = append(permissions, string(p))
+	}
+	sort.Strings(permissions)
+	return slices.Compact(permissions)
+}
+
+// Allows reports whether a granted permission set satisfies the required permission
+func Allows(granted []string, required Permission) bool {
+	return slices.Contains(granted, string(Wildcard)) || slices.Contains(granted, string(required))
+}
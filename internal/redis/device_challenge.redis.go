@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+const (
+	deviceChallengeKey = "device:challenge:%s:%s" // %s = sessionID, hashed challenge
+)
+
+// DeviceChallengeRepository keeps the outstanding challenges of device-bound sessions. Every
+// challenge has its own key and expiry, so issuing one never invalidates another.
+type DeviceChallengeRepository struct {
+	client *RedisClient
+}
+
+func NewDeviceChallengeRepository(client *RedisClient) *DeviceChallengeRepository {
+	return &DeviceChallengeRepository{client: client}
+}
+
+func (r *DeviceChallengeRepository) Save(ctx context.Context, sessionID, challengeHash string, ttl time.Duration) error {
+	if err := r.client.Client.Set(ctx, fmt.Sprintf(deviceChallengeKey, sessionID, challengeHash), 1, ttl).Err(); err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("DeviceChallengeRepository"),
+			errors.WithLocation("Save.Set"),
+			errors.WithMessage("failed to store device challenge in Redis"),
+			errors.WithErrorCode("redis/set-device-challenge-failed"),
+		)
+	}
+
+	return nil
+}
+
+// Consume removes the challenge, reporting false when it was never issued for the session,
+// expired or was already used
+func (r *DeviceChallengeRepository) Consume(ctx context.Context, sessionID, challengeHash string) (bool, error) {
+	deleted, err := r.client.Client.Del(ctx, fmt.Sprintf(deviceChallengeKey, sessionID, challengeHash)).Result()
+	if err != nil {
+		return false, errors.InternalServerError(
+			errors.WithScope("DeviceChallengeRepository"),
+			errors.WithLocation("Consume.Del"),
+			errors.WithMessage("failed to consume device challenge"),
+			errors.WithErrorCode("redis/del-device-challenge-failed"),
+		)
+	}
+
+	return deleted > 0, nil
+}
@@ -0,0 +1,95 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+const (
+	mfaChallengeKey = "mfa:challenge:%s" // %s = hashed challenge token
+)
+
+// MFAChallengeRepository keeps logins that passed the first factor while they wait for the second
+type MFAChallengeRepository struct {
+	client *RedisClient
+}
+
+func NewMFAChallengeRepository(client *RedisClient) *MFAChallengeRepository {
+	return &MFAChallengeRepository{client: client}
+}
+
+func (r *MFAChallengeRepository) Save(ctx context.Context, tokenHash string, pending dto.PendingMFALogin, ttl time.Duration) error {
+	payload, err := json.Marshal(pending)
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("MFAChallengeRepository"),
+			errors.WithLocation("Save.Marshal"),
+			errors.WithMessage("failed to encode mfa challenge"),
+			errors.WithErrorCode("redis/set-mfa-challenge-failed"),
+		)
+	}
+
+	if err := r.client.Client.Set(ctx, fmt.Sprintf(mfaChallengeKey, tokenHash), payload, ttl).Err(); err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("MFAChallengeRepository"),
+			errors.WithLocation("Save.Set"),
+			errors.WithMessage("failed to store mfa challenge in Redis"),
+			errors.WithErrorCode("redis/set-mfa-challenge-failed"),
+		)
+	}
+
+	return nil
+}
+
+func (r *MFAChallengeRepository) Get(ctx context.Context, tokenHash string) (*dto.PendingMFALogin, error) {
+	payload, err := r.client.Client.Get(ctx, fmt.Sprintf(mfaChallengeKey, tokenHash)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, errors.Unauthorized(
+				errors.WithScope("MFAChallengeRepository"),
+				errors.WithLocation("Get.NotFound"),
+				errors.WithMessage("mfa challenge not found or expired"),
+				errors.WithErrorCode("auth/mfa-challenge-invalid"),
+			)
+		}
+		return nil, errors.InternalServerError(
+			errors.WithScope("MFAChallengeRepository"),
+			errors.WithLocation("Get.RedisError"),
+			errors.WithMessage("failed to fetch mfa challenge"),
+			errors.WithErrorCode("redis/get-mfa-challenge-failed"),
+		)
+	}
+
+	var pending dto.PendingMFALogin
+	if err := json.Unmarshal(payload, &pending); err != nil {
+		return nil, errors.InternalServerError(
+			errors.WithScope("MFAChallengeRepository"),
+			errors.WithLocation("Get.Unmarshal"),
+			errors.WithMessage("failed to decode mfa challenge"),
+			errors.WithErrorCode("redis/get-mfa-challenge-failed"),
+		)
+	}
+
+	return &pending, nil
+}
+
+// Delete removes the challenge, reporting false when it was already gone so it can only complete one login
+func (r *MFAChallengeRepository) Delete(ctx context.Context, tokenHash string) (bool, error) {
+	deleted, err := r.client.Client.Del(ctx, fmt.Sprintf(mfaChallengeKey, tokenHash)).Result()
+	if err != nil {
+		return false, errors.InternalServerError(
+			errors.WithScope("MFAChallengeRepository"),
+			errors.WithLocation("Delete.Del"),
+			errors.WithMessage("failed to delete mfa challenge"),
+			errors.WithErrorCode("redis/del-mfa-challenge-failed"),
+		)
+	}
+
+	return deleted > 0, nil
+}
@@ -0,0 +1,187 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/saifoelloh/ranger/internal/constant"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type OTPVerifyResult int
+
+const (
+	OTPValid OTPVerifyResult = iota
+	OTPInvalid
+	OTPExpired
+	OTPTooManyAttempts
+)
+
+// verifyOTPScript compares the code hash and counts failed attempts atomically.
+// KEYS[1] = pending otp hash, ARGV[1] = code hash, ARGV[2] = max attempts
+var verifyOTPScript = redis.NewScript(`
+local stored = redis.call("HGET", KEYS[1], "code_hash")
+if not stored then
+	return 2
+end
+local attempts = tonumber(redis.call("HGET", KEYS[1], "attempts") or "0")
+if attempts >= tonumber(ARGV[2]) then
+	redis.call("DEL", KEYS[1])
+	return 3
+end
+if stored == ARGV[1] then
+	redis.call("DEL", KEYS[1])
+	return 0
+end
+redis.call("HINCRBY", KEYS[1], "attempts", 1)
+return 1
+`)
+
+type OTPRepository struct {
+	client *RedisClient
+}
+
+func NewOTPRepository(client *RedisClient) *OTPRepository {
+	return &OTPRepository{client: client}
+}
+
+func pendingOTPKey(action constant.OTPAction, phoneHash string) string {
+	return fmt.Sprintf("%s:%s:%s", constant.PendingOtpVerification, action, phoneHash)
+}
+
+// Save stores a hashed OTP for the phone number, resetting its attempt counter
+func (r *OTPRepository) Save(ctx context.Context, action constant.OTPAction, phoneHash, codeHash string, ttl time.Duration) error {
+	key := pendingOTPKey(action, phoneHash)
+
+	pipe := r.client.Client.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, "code_hash", codeHash, "attempts", 0)
+	pipe.Expire(ctx, key, ttl)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("OTPRepository"),
+			errors.WithLocation("Save.Exec"),
+			errors.WithMessage("failed to store otp in Redis"),
+			errors.WithErrorCode("redis/set-otp-failed"),
+		)
+	}
+
+	return nil
+}
+
+func (r *OTPRepository) Verify(ctx context.Context, action constant.OTPAction, phoneHash, codeHash string, maxAttempts int) (OTPVerifyResult, error) {
+	result, err := verifyOTPScript.Run(ctx, r.client.Client, []string{pendingOTPKey(action, phoneHash)}, codeHash, maxAttempts).Int()
+	if err != nil {
+		return OTPInvalid, errors.InternalServerError(
+			errors.WithScope("OTPRepository"),
+			errors.WithLocation("Verify.Run"),
+			errors.WithMessage("failed to verify otp"),
+			errors.WithErrorCode("redis/verify-otp-failed"),
+			errors.WithDetail(err.Error()),
+		)
+	}
+
+	return OTPVerifyResult(result), nil
+}
+
+// AcquireCooldown reports whether a new OTP may be sent, blocking further sends for ttl
+func (r *OTPRepository) AcquireCooldown(ctx context.Context, action constant.OTPAction, phoneHash string, ttl time.Duration) (bool, error) {
+	key := fmt.Sprintf("%s:%s:%s", constant.OtpAction, action, phoneHash)
+	acquired, err := r.client.Client.SetNX(ctx, key, 1, ttl).Result()
+	if err != nil {
+		return false, errors.InternalServerError(
+			errors.WithScope("OTPRepository"),
+			errors.WithLocation("AcquireCooldown.SetNX"),
+			errors.WithMessage("failed to check otp cooldown"),
+			errors.WithErrorCode("redis/set-otp-failed"),
+		)
+	}
+
+	return acquired, nil
+}
+
+// GetMockedOTP returns the fixed code configured for a test account, if any
+func (r *OTPRepository) GetMockedOTP(ctx context.Context, phoneHash string) (string, bool, error) {
+	code, err := r.client.Client.Get(ctx, fmt.Sprintf("%s:%s", constant.MockedOtp, phoneHash)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", false, nil
+		}
+		return "", false, errors.InternalServerError(
+			errors.WithScope("OTPRepository"),
+			errors.WithLocation("GetMockedOTP.Get"),
+			errors.WithMessage("failed to read mocked otp"),
+			errors.WithErrorCode("redis/get-otp-failed"),
+		)
+	}
+
+	return code, true, nil
+}
+
+// MarkVerified remembers that the phone number was just proven to belong to the caller
+func (r *OTPRepository) MarkVerified(ctx context.Context, phoneHash string, ttl time.Duration) error {
+	err := r.client.Client.Set(ctx, fmt.Sprintf("%s:%s", constant.VerifiedNumber, phoneHash), 1, ttl).Err()
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("OTPRepository"),
+			errors.WithLocation("MarkVerified.Set"),
+			errors.WithMessage("failed to mark phone number as verified"),
+			errors.WithErrorCode("redis/set-otp-failed"),
+		)
+	}
+
+	return nil
+}
+
+// IsVerified reports whether the phone number was recently verified, leaving the mark in place
+func (r *OTPRepository) IsVerified(ctx context.Context, phoneHash string) (bool, error) {
+	exists, err := r.client.Client.Exists(ctx, fmt.Sprintf("%s:%s", constant.VerifiedNumber, phoneHash)).Result()
+	if err != nil {
+		return false, errors.InternalServerError(
+			errors.WithScope("OTPRepository"),
+			errors.WithLocation("IsVerified.Exists"),
+			errors.WithMessage("failed to check verified phone number"),
+			errors.WithErrorCode("redis/get-otp-failed"),
+		)
+	}
+
+	return exists > 0, nil
+}
+
+// ConsumeVerified reports whether the phone number was recently verified and clears the mark
+func (r *OTPRepository) ConsumeVerified(ctx context.Context, phoneHash string) (bool, error) {
+	err := r.client.Client.GetDel(ctx, fmt.Sprintf("%s:%s", constant.VerifiedNumber, phoneHash)).Err()
+	if err != nil {
+		if err == redis.Nil {
+			return false, nil
+		}
+		return false, errors.InternalServerError(
+			errors.WithScope("OTPRepository"),
+			errors.WithLocation("ConsumeVerified.GetDel"),
+			errors.WithMessage("failed to check verified phone number"),
+			errors.WithErrorCode("redis/get-otp-failed"),
+		)
+	}
+
+	return true, nil
+}
+
+// AcquireEmailCodeCooldown reports whether a new email verification code may be sent, blocking
+// further sends for ttl
+func (r *OTPRepository) AcquireEmailCodeCooldown(ctx context.Context, emailHash string, ttl time.Duration) (bool, error) {
+	key := fmt.Sprintf("%s:%s", constant.ResendEmailCode, emailHash)
+	acquired, err := r.client.Client.SetNX(ctx, key, 1, ttl).Result()
+	if err != nil {
+		return false, errors.InternalServerError(
+			errors.WithScope("OTPRepository"),
+			errors.WithLocation("AcquireEmailCodeCooldown.SetNX"),
+			errors.WithMessage("failed to check email verification cooldown"),
+			errors.WithErrorCode("redis/set-otp-failed"),
+		)
+	}
+
+	return acquired, nil
+}
@@ -0,0 +1,74 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/saifoelloh/ranger/internal/constant"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type PasswordResetRepository struct {
+	client *RedisClient
+}
+
+func NewPasswordResetRepository(client *RedisClient) *PasswordResetRepository {
+	return &PasswordResetRepository{client: client}
+}
+
+// SaveToken registers a reset token id so it can be redeemed exactly once
+func (r *PasswordResetRepository) SaveToken(ctx context.Context, tokenID, userID string, ttl time.Duration) error {
+	key := fmt.Sprintf("%s:%s", constant.RequestChangePassword, tokenID)
+	if err := r.client.Client.Set(ctx, key, userID, ttl).Err(); err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("PasswordResetRepository"),
+			errors.WithLocation("SaveToken.Set"),
+			errors.WithMessage("failed to store password reset token"),
+			errors.WithErrorCode("redis/set-reset-token-failed"),
+		)
+	}
+
+	return nil
+}
+
+// ConsumeToken redeems a reset token id and returns the user it was issued to
+func (r *PasswordResetRepository) ConsumeToken(ctx context.Context, tokenID string) (string, error) {
+	key := fmt.Sprintf("%s:%s", constant.RequestChangePassword, tokenID)
+	userID, err := r.client.Client.GetDel(ctx, key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", errors.Unauthorized(
+				errors.WithScope("PasswordResetRepository"),
+				errors.WithLocation("ConsumeToken.NotFound"),
+				errors.WithMessage("reset token is invalid, expired or already used"),
+				errors.WithErrorCode("auth/invalid-reset-token"),
+			)
+		}
+		return "", errors.InternalServerError(
+			errors.WithScope("PasswordResetRepository"),
+			errors.WithLocation("ConsumeToken.GetDel"),
+			errors.WithMessage("failed to redeem password reset token"),
+			errors.WithErrorCode("redis/get-reset-token-failed"),
+		)
+	}
+
+	return userID, nil
+}
+
+// AcquireCooldown reports whether a new reset email may be sent to the user
+func (r *PasswordResetRepository) AcquireCooldown(ctx context.Context, userID string, ttl time.Duration) (bool, error) {
+	key := fmt.Sprintf("%s:%s", constant.ChangePassword, userID)
+	acquired, err := r.client.Client.SetNX(ctx, key, 1, ttl).Result()
+	if err != nil {
+		return false, errors.InternalServerError(
+			errors.WithScope("PasswordResetRepository"),
+			errors.WithLocation("AcquireCooldown.SetNX"),
+			errors.WithMessage("failed to check password reset cooldown"),
+			errors.WithErrorCode("redis/set-reset-token-failed"),
+		)
+	}
+
+	return acquired, nil
+}
@@ -3,26 +3,237 @@ package redis
 import (
 	"context"
 	"fmt"
+	"log"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"github.com/saifoelloh/ranger/internal/ratelimit"
 	"github.com/saifoelloh/ranger/pkg/errors"
 )
 
 const (
-	loginRateLimitKey = "rate-limit:login:%s"  // %s = ip
-	apiRateLimitKey   = "rate-limit:api:%s:%s" // %s = userID, endpoint
+	loginRateLimitKey    = "rate-limit:login:%s:%s" // %s = dimension, identifier
+	mfaRateLimitKey      = "rate-limit:mfa:%s"      // %s = userID
+	passwordRateLimitKey = "rate-limit:password:%s" // %s = userID
+	pinRateLimitKey      = "rate-limit:pin:%s"      // %s = userID
+	emailRateLimitKey    = "rate-limit:email:%s"    // %s = email hash
+	apiRateLimitKey      = "rate-limit:api:%s:%s"   // %s = userID or ip:<address>, endpoint
+
+	delaySuffix    = ":delay"    // progressive delay of a counter
+	offensesSuffix = ":offenses" // lockouts of a counter within the offense window
+)
+
+// LoginDimension is one of the independent counters a login attempt is throttled on
+type LoginDimension string
+
+const (
+	LoginDimensionAccount LoginDimension = "account"
+	LoginDimensionIP      LoginDimension = "ip"
+	LoginDimensionSubnet  LoginDimension = "subnet"
+	LoginDimensionDevice  LoginDimension = "device"
 )
 
+var loginDimensions = []LoginDimension{LoginDimensionAccount, LoginDimensionIP, LoginDimensionSubnet, LoginDimensionDevice}
+
+// attemptScript checks every counter before counting the attempt on all of them, so an attempt
+// rejected by one counter is not charged to the others. Every counter has three keys: the attempt
+// count, the progressive delay and the offenses remembered across lockouts. A counter is locked on
+// its first rejected attempt, for longer each time the same counter was locked within the offense
+// window, and later rejected attempts do not extend the lockout.
+// KEYS = count, delay and offenses key of every counter in order.
+// ARGV[1..4] = max delay ms, offense window ms, lockout multiplier, max lockout ms, followed by
+// max attempts, window ms, lockout ms and delay ms of every counter in order. A zero max disables the cap.
+// Returns {0, 0, 0} when allowed, otherwise {index of the blocking counter, retry after ms, attempts left}.
+var attemptScript = redis.NewScript(`
+local maxDelay, offenseWindow = tonumber(ARGV[1]), tonumber(ARGV[2])
+local multiplier, maxLockout = tonumber(ARGV[3]), tonumber(ARGV[4])
+
+local blocked, retryAfter, remaining = 0, 0, 0
+for i = 1, #KEYS / 3 do
+	local count, delay, offenses = KEYS[i * 3 - 2], KEYS[i * 3 - 1], KEYS[i * 3]
+	local max, lockout = tonumber(ARGV[i * 4 + 1]), tonumber(ARGV[i * 4 + 3])
+	local attempts = tonumber(redis.call("GET", count) or "0")
+
+	local wait, left = 0, 0
+	if attempts >= max then
+		if attempts == max then
+			local offense = redis.call("INCR", offenses)
+			redis.call("PEXPIRE", offenses, offenseWindow)
+			local duration = lockout * multiplier ^ (offense - 1)
+			if maxLockout > 0 then
+				duration = math.min(duration, maxLockout)
+			end
+			redis.call("PEXPIRE", count, math.floor(duration))
+			redis.call("DEL", delay)
+		end
+		redis.call("INCR", count)
+		wait = redis.call("PTTL", count)
+	else
+		wait = redis.call("PTTL", delay)
+		left = max - attempts
+	end
+
+	if wait > 0 and (blocked == 0 or wait > retryAfter) then
+		blocked, retryAfter, remaining = i, wait, left
+	end
+end
+if blocked > 0 then
+	return {blocked, retryAfter, remaining}
+end
+
+for i = 1, #KEYS / 3 do
+	local count, delay = KEYS[i * 3 - 2], KEYS[i * 3 - 1]
+	local max, window, base = tonumber(ARGV[i * 4 + 1]), tonumber(ARGV[i * 4 + 2]), tonumber(ARGV[i * 4 + 4])
+	local attempts = redis.call("INCR", count)
+	if attempts == 1 then
+		redis.call("PEXPIRE", count, window)
+	end
+	if base > 0 and attempts < max then
+		local wait = base * 2 ^ (attempts - 1)
+		if maxDelay > 0 then
+			wait = math.min(wait, maxDelay)
+		end
+		redis.call("SET", delay, 1, "PX", math.floor(wait))
+	end
+end
+return {0, 0, 0}
+`)
+
+// refundScript takes one attempt back from every counter that still holds one, without
+// touching its expiry, delay or offenses.
+// KEYS = count key of every counter.
+var refundScript = redis.NewScript(`
+for i = 1, #KEYS do
+	if tonumber(redis.call("GET", KEYS[i]) or "0") > 0 then
+		redis.call("DECR", KEYS[i])
+	end
+end
+return 0
+`)
+
+// tokenBucketScript refills the bucket for the time elapsed since the last request and takes a token.
+// KEYS[1] = bucket hash, ARGV[1] = capacity, ARGV[2] = tokens refilled per window, ARGV[3] = window ms.
+// Returns {allowed, tokens left, retry after ms, ms until the bucket is full}.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2]) / tonumber(ARGV[3])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1]) or capacity
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed, retryAfter = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retryAfter = math.ceil((1 - tokens) / rate)
+end
+
+local reset = math.ceil((capacity - tokens) / rate)
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.max(reset, 1))
+return {allowed, math.floor(tokens), retryAfter, reset}
+`)
+
+// slidingWindowLogScript logs request timestamps and counts the ones inside the window.
+// KEYS[1] = log sorted set, ARGV[1] = limit, ARGV[2] = window ms, ARGV[3] = unique request id.
+// Returns {allowed, requests left, retry after ms, ms until the oldest request leaves the window}.
+var slidingWindowLogScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+
+local allowed = 0
+if count < limit then
+	redis.call("ZADD", KEYS[1], now, ARGV[3])
+	redis.call("PEXPIRE", KEYS[1], window)
+	count = count + 1
+	allowed = 1
+end
+
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+local reset = window
+if oldest[2] then
+	reset = math.max(0, tonumber(oldest[2]) + window - now)
+end
+
+local retryAfter = 0
+if allowed == 0 then
+	retryAfter = reset
+end
+return {allowed, limit - count, retryAfter, reset}
+`)
+
+// APIQuotaResult is the outcome of counting a request against its quota
+type APIQuotaResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration // until the quota is fully restored
+}
+
 type RateLimiterRepository struct {
 	client *RedisClient
 	cfg    RateLimiterConfig
 }
 
 type RateLimiterConfig struct {
-	MaxAttempts     int
-	DelayPerAttempt time.Duration
-	LockoutDuration time.Duration
+	Attempts RateLimit                    // mfa, password, pin and email verification counters
+	Login    map[LoginDimension]RateLimit // a dimension without a limit is not counted
+	Backoff  Backoff
+	FailOpen bool // let attempts through instead of rejecting them when Redis is unreachable
+}
+
+// RateLimit is the threshold of a single counter
+type RateLimit struct {
+	MaxAttempts int
+	Window      time.Duration // starts on the first attempt
+	Lockout     time.Duration // first lockout, escalated for repeat offenders
+	Delay       time.Duration // wait after the first attempt, doubled after every following one; 0 disables
+}
+
+// Backoff bounds the progressive delays and the escalating lockouts shared by every counter
+type Backoff struct {
+	MaxDelay          time.Duration
+	OffenseWindow     time.Duration // how long a lockout is remembered
+	LockoutMultiplier int           // every remembered lockout multiplies the next one
+	MaxLockout        time.Duration
+}
+
+// LoginAttempt identifies a login attempt along each dimension, empty values are not counted
+type LoginAttempt struct {
+	Account string // email hash or sso identity
+	IP      string
+	Subnet  string
+	Device  string
+}
+
+func (a LoginAttempt) identifier(dimension LoginDimension) string {
+	switch dimension {
+	case LoginDimensionAccount:
+		return a.Account
+	case LoginDimensionIP:
+		return a.IP
+	case LoginDimensionSubnet:
+		return a.Subnet
+	case LoginDimensionDevice:
+		return a.Device
+	}
+	return ""
 }
 
 func NewRateLimiterRepository(client *RedisClient, cfg RateLimiterConfig) *RateLimiterRepository {
@@ -32,102 +243,399 @@ func NewRateLimiterRepository(client *RedisClient, cfg RateLimiterConfig) *RateL
 	}
 }
 
-func (r *RateLimiterRepository) IsAllowed(ctx context.Context, ip string) error {
-	key := fmt.Sprintf(loginRateLimitKey, ip)
-	cmd := r.client.Client
+// IsLoginAllowed counts a login attempt against the account, IP, subnet and device counters in
+// one round-trip, rejecting it while any of them is locked. ResetLogin takes a successful
+// attempt back from the IP and subnet counters.
+func (r *RateLimiterRepository) IsLoginAllowed(ctx context.Context, attempt LoginAttempt) error {
+	var counters []string
+	var limits []RateLimit
 
-	err := cmd.Watch(ctx, func(tx *redis.Tx) error {
-		// Cek apakah key ada
-		_, err := tx.Get(ctx, key).Result()
-		if err != nil && err != redis.Nil {
-			return errors.InternalServerError(
-				errors.WithScope("RateLimiter"),
-				errors.WithLocation("IsAllowed.Get"),
-				errors.WithMessage("failed to get rate limit data"),
-				errors.WithErrorCode("redis/get-error"),
-			)
+	for _, dimension := range loginDimensions {
+		id := attempt.identifier(dimension)
+		limit, ok := r.cfg.Login[dimension]
+		if id == "" || !ok || limit.MaxAttempts <= 0 {
+			continue
 		}
+		counters = append(counters, fmt.Sprintf(loginRateLimitKey, dimension, id))
+		limits = append(limits, limit)
+	}
+	if len(counters) == 0 {
+		return nil
+	}
+
+	return r.take(ctx, "IsLoginAllowed", counters, limits, "too many login attempts. try again later")
+}
+
+// IsMFAAllowed counts a second-factor attempt for the user and locks further attempts out
+func (r *RateLimiterRepository) IsMFAAllowed(ctx context.Context, userID string) error {
+	return r.isAllowed(ctx, fmt.Sprintf(mfaRateLimitKey, userID), "too many verification attempts. try again later")
+}
 
-		var attempts int64 = 0
-		if err == nil {
-			attempts, err = tx.Get(ctx, key).Int64()
-			if err != nil {
-				return errors.InternalServerError(
-					errors.WithScope("RateLimiter"),
-					errors.WithLocation("IsAllowed.Parse"),
-					errors.WithMessage("failed to parse attempts count"),
-					errors.WithErrorCode("redis/parse-error"),
-				)
+// IsPasswordAllowed counts a current-password check for the user and locks further attempts out
+func (r *RateLimiterRepository) IsPasswordAllowed(ctx context.Context, userID string) error {
+	return r.isAllowed(ctx, fmt.Sprintf(passwordRateLimitKey, userID), "too many password attempts. try again later")
+}
+
+// IsPinAllowed counts a PIN attempt for the user and locks further attempts out
+func (r *RateLimiterRepository) IsPinAllowed(ctx context.Context, userID string) error {
+	return r.isAllowed(ctx, fmt.Sprintf(pinRateLimitKey, userID), "too many pin attempts. try again later")
+}
+
+// IsEmailVerificationAllowed counts an email verification attempt and locks further attempts out
+func (r *RateLimiterRepository) IsEmailVerificationAllowed(ctx context.Context, emailHash string) error {
+	return r.isAllowed(ctx, fmt.Sprintf(emailRateLimitKey, emailHash), "too many verification attempts. try again later")
+}
+
+// isAllowed counts an attempt against the generic attempts limit of key
+func (r *RateLimiterRepository) isAllowed(ctx context.Context, key, lockoutMessage string) error {
+	return r.take(ctx, "IsAllowed", []string{key}, []RateLimit{r.cfg.Attempts}, lockoutMessage)
+}
+
+// take runs attemptScript over the counters, rejecting the attempt while any of them is locked or delayed
+func (r *RateLimiterRepository) take(ctx context.Context, location string, counters []string, limits []RateLimit, message string) error {
+	result, err := r.runAttempt(ctx, counters, limits)
+	if err != nil {
+		return r.unavailable(location+".Run", err)
+	}
+	if result[0] == 0 {
+		return nil
+	}
+
+	limit := limits[result[0]-1]
+	return r.lockedOut(message, limit.MaxAttempts, int(result[2]), time.Duration(result[1])*time.Millisecond)
+}
+
+// runAttempt passes the counters and the backoff settings to attemptScript and returns its result
+func (r *RateLimiterRepository) runAttempt(ctx context.Context, counters []string, limits []RateLimit) ([]int64, error) {
+	keys := make([]string, 0, len(counters)*3)
+	args := []interface{}{
+		r.cfg.Backoff.MaxDelay.Milliseconds(),
+		r.cfg.Backoff.OffenseWindow.Milliseconds(),
+		max(r.cfg.Backoff.LockoutMultiplier, 1),
+		r.cfg.Backoff.MaxLockout.Milliseconds(),
+	}
+	for i, counter := range counters {
+		keys = append(keys, counter, counter+delaySuffix, counter+offensesSuffix)
+		limit := limits[i]
+		args = append(args, limit.MaxAttempts, limit.Window.Milliseconds(), limit.Lockout.Milliseconds(), limit.Delay.Milliseconds())
+	}
+
+	return attemptScript.Run(ctx, r.client.Client, keys, args...).Int64Slice()
+}
+
+// lockedOut builds the lockout error, the Extra entries are sent as response headers
+func (r *RateLimiterRepository) lockedOut(message string, maxAttempts, remaining int, retryAfter time.Duration) error {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	return errors.TooManyRequests(
+		errors.WithScope("RateLimiter"),
+		errors.WithLocation("IsAllowed.AttemptsExceeded"),
+		errors.WithMessage(message),
+		errors.WithErrorCode("auth/too-many-attempts"),
+		errors.WithExtra("Retry-After", strconv.FormatInt(seconds, 10)),
+		errors.WithExtra("X-RateLimit-Limit", strconv.Itoa(maxAttempts)),
+		errors.WithExtra("X-RateLimit-Remaining", strconv.Itoa(remaining)),
+		errors.WithExtra("X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix()+seconds, 10)),
+	)
+}
+
+// unavailable applies the fail mode when Redis cannot be reached: fail open lets the
+// attempt through, fail closed rejects it
+func (r *RateLimiterRepository) unavailable(location string, err error) error {
+	if r.cfg.FailOpen {
+		log.Printf("[WARN] RateLimiter/%s - rate limiter unavailable, allowing attempt: %v", location, err)
+		return nil
+	}
+
+	return errors.ServiceUnavailable(
+		errors.WithScope("RateLimiter"),
+		errors.WithLocation(location),
+		errors.WithMessage("service temporarily unavailable. try again later"),
+		errors.WithErrorCode("redis/rate-limiter-unavailable"),
+		errors.WithDetail(err.Error()),
+	)
+}
+
+// TakeAPIQuota counts a request of the user, or of an address for public routes, on endpoint against quota. A nil result means Redis
+// could not be reached and the limiter failed open.
+func (r *RateLimiterRepository) TakeAPIQuota(ctx context.Context, userID, endpoint string, quota ratelimit.Quota) (*APIQuotaResult, error) {
+	// The algorithm is part of the key, both store a different Redis type
+	key := fmt.Sprintf(apiRateLimitKey, userID, string(quota.Algorithm)+":"+endpoint)
+
+	var cmd *redis.Cmd
+	limit := quota.Limit
+	switch quota.Algorithm {
+	case ratelimit.SlidingWindowLog:
+		cmd = slidingWindowLogScript.Run(ctx, r.client.Client, []string{key}, quota.Limit, quota.Window.Milliseconds(), uuid.NewString())
+	default:
+		limit = quota.Limit + quota.Burst
+		cmd = tokenBucketScript.Run(ctx, r.client.Client, []string{key}, limit, quota.Limit, quota.Window.Milliseconds())
+	}
+
+	result, err := cmd.Int64Slice()
+	if err != nil {
+		return nil, r.unavailable("TakeAPIQuota.Run", err)
+	}
+
+	return &APIQuotaResult{
+		Allowed:    result[0] == 1,
+		Limit:      limit,
+		Remaining:  int(result[1]),
+		RetryAfter: time.Duration(result[2]) * time.Millisecond,
+		Reset:      time.Duration(result[3]) * time.Millisecond,
+	}, nil
+}
+
+// ResetLogin clears the account and device counters after a successful login. The IP and
+// subnet counters only keep failures: the successful attempt is taken back from them, but they
+// are not cleared, one valid account must not wipe the failures sprayed from an address.
+// Remembered offenses are kept as well, see reset.
+func (r *RateLimiterRepository) ResetLogin(ctx context.Context, attempt LoginAttempt) error {
+	for _, dimension := range []LoginDimension{LoginDimensionAccount, LoginDimensionDevice} {
+		if id := attempt.identifier(dimension); id != "" {
+			if err := r.reset(ctx, fmt.Sprintf(loginRateLimitKey, dimension, id)); err != nil {
+				return err
 			}
 		}
+	}
 
-		if attempts >= int64(r.cfg.MaxAttempts) {
-			// Lockout: extend expiration
-			_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
-				pipe.Expire(ctx, key, r.cfg.LockoutDuration)
-				return nil
+	var counters []string
+	for _, dimension := range []LoginDimension{LoginDimensionIP, LoginDimensionSubnet} {
+		if _, ok := r.cfg.Login[dimension]; ok && attempt.identifier(dimension) != "" {
+			counters = append(counters, fmt.Sprintf(loginRateLimitKey, dimension, attempt.identifier(dimension)))
+		}
+	}
+	if len(counters) == 0 {
+		return nil
+	}
+
+	if err := refundScript.Run(ctx, r.client.Client, counters).Err(); err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("RateLimiter"),
+			errors.WithLocation("ResetLogin.Refund"),
+			errors.WithMessage("failed to reset rate limit"),
+			errors.WithErrorCode("redis/refund-rate-limit-failed"),
+		)
+	}
+	return nil
+}
+
+func (r *RateLimiterRepository) ResetMFA(ctx context.Context, userID string) error {
+	return r.reset(ctx, fmt.Sprintf(mfaRateLimitKey, userID))
+}
+
+func (r *RateLimiterRepository) ResetPassword(ctx context.Context, userID string) error {
+	return r.reset(ctx, fmt.Sprintf(passwordRateLimitKey, userID))
+}
+
+func (r *RateLimiterRepository) ResetPin(ctx context.Context, userID string) error {
+	return r.reset(ctx, fmt.Sprintf(pinRateLimitKey, userID))
+}
+
+func (r *RateLimiterRepository) ResetEmailVerification(ctx context.Context, emailHash string) error {
+	return r.reset(ctx, fmt.Sprintf(emailRateLimitKey, emailHash))
+}
+
+// reset clears the short-term attempt count and delay of a counter, its remembered offenses keep
+// escalating later lockouts until the offense window expires
+func (r *RateLimiterRepository) reset(ctx context.Context, key string) error {
+	err := r.client.Client.Del(ctx, key, key+delaySuffix).Err()
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("RateLimiter"),
+			errors.WithLocation("Reset.Del"),
+			errors.WithMessage("failed to reset rate limit"),
+			errors.WithErrorCode("redis/del-rate-limit-failed"),
+		)
+	}
+	return nil
+}
+
+// LimiterCounter is an attempt counter that can be inspected or cleared by support staff
+type LimiterCounter struct {
+	Dimension string // login dimension or mfa, password, pin, email-verification
+	Subject   string // what the counter is keyed on, for display
+	key       string
+	limit     RateLimit
+}
+
+func (c LimiterCounter) MaxAttempts() int {
+	return c.limit.MaxAttempts
+}
+
+// CounterState is the current state of a counter. A counter at its threshold is not locked yet:
+// attemptScript locks it on the next attempt, for NextLockout.
+type CounterState struct {
+	LimiterCounter
+	Attempts    int
+	Locked      bool
+	AtThreshold bool
+	RetryAfter  time.Duration // until the lockout or the progressive delay ends
+	NextLockout time.Duration // lockout the next attempt would start, set at the threshold
+	ResetIn     time.Duration // until the attempt count expires
+	Offenses    int           // lockouts remembered within the offense window
+}
+
+// UserCounters returns the counters of a user: the login account counters of every account
+// identifier and the login device counters of every device, both keyed by subject, and the mfa,
+// password, pin and email verification counters
+func (r *RateLimiterRepository) UserCounters(userID string, accounts map[string]string, devices []string, emailHash string) []LimiterCounter {
+	counters := []LimiterCounter{}
+	if limit, ok := r.cfg.Login[LoginDimensionAccount]; ok {
+		for _, subject := range slices.Sorted(maps.Keys(accounts)) {
+			counters = append(counters, LimiterCounter{
+				Dimension: string(LoginDimensionAccount),
+				Subject:   subject,
+				key:       fmt.Sprintf(loginRateLimitKey, LoginDimensionAccount, accounts[subject]),
+				limit:     limit,
+			})
+		}
+	}
+	if limit, ok := r.cfg.Login[LoginDimensionDevice]; ok {
+		for _, device := range devices {
+			counters = append(counters, LimiterCounter{
+				Dimension: string(LoginDimensionDevice),
+				Subject:   device,
+				key:       fmt.Sprintf(loginRateLimitKey, LoginDimensionDevice, device),
+				limit:     limit,
 			})
-			if err != nil {
-				return errors.InternalServerError(
-					errors.WithScope("RateLimiter"),
-					errors.WithLocation("IsAllowed.Expire"),
-					errors.WithMessage("failed to extend lockout"),
-					errors.WithErrorCode("redis/expire-error"),
-				)
-			}
-			return errors.TooManyRequests(
-				errors.WithScope("RateLimiter"),
-				errors.WithLocation("IsAllowed.AttemptsExceeded"),
-				errors.WithMessage("too many login attempts. try again later"),
-				errors.WithErrorCode("auth/too-many-attempts"),
-			)
 		}
+	}
 
-		// Tambah attempts dan set TTL jika baru
-		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
-			pipe.Incr(ctx, key)
-			if attempts == 0 {
-				pipe.Expire(ctx, key, r.cfg.DelayPerAttempt)
-			}
-			return nil
+	for _, counter := range []struct{ dimension, format string }{
+		{"mfa", mfaRateLimitKey},
+		{"password", passwordRateLimitKey},
+		{"pin", pinRateLimitKey},
+	} {
+		counters = append(counters, LimiterCounter{
+			Dimension: counter.dimension,
+			Subject:   userID,
+			key:       fmt.Sprintf(counter.format, userID),
+			limit:     r.cfg.Attempts,
+		})
+	}
+	if emailHash != "" {
+		counters = append(counters, LimiterCounter{
+			Dimension: "email-verification",
+			Subject:   userID,
+			key:       fmt.Sprintf(emailRateLimitKey, emailHash),
+			limit:     r.cfg.Attempts,
 		})
-		if err != nil {
-			return errors.InternalServerError(
-				errors.WithScope("RateLimiter"),
-				errors.WithLocation("IsAllowed.Incr"),
-				errors.WithMessage("failed to increment rate limit"),
-				errors.WithErrorCode("redis/incr-error"),
-			)
+	}
+
+	return counters
+}
+
+// IPCounters returns the login counters of an address and its subnet
+func (r *RateLimiterRepository) IPCounters(ip, subnet string) []LimiterCounter {
+	counters := []LimiterCounter{}
+	ids := []string{ip, subnet}
+	for i, dimension := range []LoginDimension{LoginDimensionIP, LoginDimensionSubnet} {
+		id := ids[i]
+		limit, ok := r.cfg.Login[dimension]
+		if !ok || id == "" {
+			continue
 		}
+		counters = append(counters, LimiterCounter{
+			Dimension: string(dimension),
+			Subject:   id,
+			key:       fmt.Sprintf(loginRateLimitKey, dimension, id),
+			limit:     limit,
+		})
+	}
+	return counters
+}
 
-		return nil
-	}, key)
+// Inspect reads the state of the counters in one round-trip
+func (r *RateLimiterRepository) Inspect(ctx context.Context, counters []LimiterCounter) ([]CounterState, error) {
+	type reads struct {
+		attempts, offenses *redis.StringCmd
+		ttl, delay         *redis.DurationCmd
+	}
+	cmds := make([]reads, len(counters))
 
-	if err != nil {
-		// Return kalau memang error dari custom error handler di atas
-		if e, ok := err.(*errors.Extension); ok {
-			return e
+	_, err := r.client.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, counter := range counters {
+			cmds[i] = reads{
+				attempts: pipe.Get(ctx, counter.key),
+				ttl:      pipe.PTTL(ctx, counter.key),
+				delay:    pipe.PTTL(ctx, counter.key+delaySuffix),
+				offenses: pipe.Get(ctx, counter.key+offensesSuffix),
+			}
 		}
-		return errors.InternalServerError(
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, errors.InternalServerError(
 			errors.WithScope("RateLimiter"),
-			errors.WithLocation("IsAllowed.Watch"),
-			errors.WithMessage("unexpected error during rate limiting"),
-			errors.WithErrorCode("redis/watch-transaction-error"),
+			errors.WithLocation("Inspect.Pipelined"),
+			errors.WithMessage("failed to read rate limit data"),
+			errors.WithErrorCode("redis/get-rate-limit-failed"),
 			errors.WithDetail(err.Error()),
 		)
 	}
 
-	return nil
+	states := make([]CounterState, len(counters))
+	for i, counter := range counters {
+		attempts, _ := cmds[i].attempts.Int()
+		offenses, _ := cmds[i].offenses.Int()
+		states[i] = r.counterState(counter, attempts, offenses, max(cmds[i].ttl.Val(), 0), max(cmds[i].delay.Val(), 0))
+	}
+
+	return states, nil
+}
+
+// counterState mirrors attemptScript: the attempt that finds a counter at its threshold is
+// rejected, locks the counter and is counted, so only a count past the threshold is locked
+func (r *RateLimiterRepository) counterState(counter LimiterCounter, attempts, offenses int, ttl, delay time.Duration) CounterState {
+	state := CounterState{
+		LimiterCounter: counter,
+		Attempts:       attempts,
+		ResetIn:        ttl,
+		Offenses:       offenses,
+	}
+
+	maxAttempts := counter.limit.MaxAttempts
+	switch {
+	case maxAttempts > 0 && attempts > maxAttempts:
+		state.Locked = true
+		state.RetryAfter = ttl
+	case maxAttempts > 0 && attempts == maxAttempts:
+		state.AtThreshold = true
+		state.NextLockout = r.lockoutAfter(counter.limit.Lockout, offenses)
+		state.RetryAfter = delay
+	default:
+		state.RetryAfter = delay
+	}
+
+	return state
 }
 
-func (r *RateLimiterRepository) Reset(ctx context.Context, ip string) error {
-	key := fmt.Sprintf(loginRateLimitKey, ip)
-	err := r.client.Client.Del(ctx, key).Err()
+// lockoutAfter is the lockout attemptScript applies to a counter with offenses remembered lockouts
+func (r *RateLimiterRepository) lockoutAfter(lockout time.Duration, offenses int) time.Duration {
+	duration := float64(lockout) * math.Pow(float64(max(r.cfg.Backoff.LockoutMultiplier, 1)), float64(offenses))
+	if r.cfg.Backoff.MaxLockout > 0 {
+		duration = math.Min(duration, float64(r.cfg.Backoff.MaxLockout))
+	}
+	return time.Duration(duration)
+}
+
+// Clear unlocks the counters, forgetting their remembered offenses as well
+func (r *RateLimiterRepository) Clear(ctx context.Context, counters []LimiterCounter) error {
+	if len(counters) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(counters)*3)
+	for _, counter := range counters {
+		keys = append(keys, counter.key, counter.key+delaySuffix, counter.key+offensesSuffix)
+	}
+
+	err := r.client.Client.Del(ctx, keys...).Err()
 	if err != nil {
 		return errors.InternalServerError(
 			errors.WithScope("RateLimiter"),
-			errors.WithLocation("Reset.Del"),
-			errors.WithMessage("failed to reset rate limit"),
+			errors.WithLocation("Clear.Del"),
+			errors.WithMessage("failed to clear rate limit"),
 			errors.WithErrorCode("redis/del-rate-limit-failed"),
 		)
 	}
@@ -0,0 +1,201 @@
+package redis
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestLimiter runs the limiter against an in-memory Redis whose clock only moves with FastForward
+func newTestLimiter(t *testing.T, backoff Backoff) (*RateLimiterRepository, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRateLimiterRepository(NewRedisClient(client), RateLimiterConfig{Backoff: backoff}), mr
+}
+
+// attempt runs attemptScript once and fails the test unless it returns want
+func attempt(t *testing.T, r *RateLimiterRepository, counters []string, limits []RateLimit, want []int64) {
+	t.Helper()
+	got, err := r.runAttempt(context.Background(), counters, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("attemptScript = %v, want %v", got, want)
+	}
+}
+
+func TestAttemptScriptDelay(t *testing.T) {
+	r, mr := newTestLimiter(t, Backoff{MaxDelay: 3 * time.Second})
+	counters := []string{"counter"}
+	limits := []RateLimit{{MaxAttempts: 5, Window: 15 * time.Minute, Lockout: 10 * time.Minute, Delay: time.Second}}
+
+	attempt(t, r, counters, limits, []int64{0, 0, 0})
+	attempt(t, r, counters, limits, []int64{1, 1000, 4})
+	if got, _ := mr.Get("counter"); got != "1" {
+		t.Errorf("attempts = %s after a delayed attempt, want 1", got)
+	}
+
+	// The delay doubles after every counted attempt, up to the max delay
+	mr.FastForward(time.Second)
+	attempt(t, r, counters, limits, []int64{0, 0, 0})
+	attempt(t, r, counters, limits, []int64{1, 2000, 3})
+
+	mr.FastForward(2 * time.Second)
+	attempt(t, r, counters, limits, []int64{0, 0, 0})
+	attempt(t, r, counters, limits, []int64{1, 3000, 2})
+}
+
+func TestAttemptScriptLockout(t *testing.T) {
+	r, mr := newTestLimiter(t, Backoff{OffenseWindow: 24 * time.Hour, LockoutMultiplier: 1})
+	counters := []string{"counter"}
+	limits := []RateLimit{{MaxAttempts: 3, Window: 15 * time.Minute, Lockout: 10 * time.Minute}}
+
+	for range 3 {
+		attempt(t, r, counters, limits, []int64{0, 0, 0})
+	}
+	if ttl := mr.TTL("counter"); ttl != 15*time.Minute {
+		t.Errorf("window = %v, want %v", ttl, 15*time.Minute)
+	}
+
+	// The attempt that finds the threshold reached starts the lockout
+	attempt(t, r, counters, limits, []int64{1, 600000, 0})
+
+	// Later rejected attempts do not extend it
+	mr.FastForward(time.Minute)
+	attempt(t, r, counters, limits, []int64{1, 540000, 0})
+	if got, _ := mr.Get("counter:offenses"); got != "1" {
+		t.Errorf("offenses = %s, want 1", got)
+	}
+
+	mr.FastForward(9 * time.Minute)
+	attempt(t, r, counters, limits, []int64{0, 0, 0})
+}
+
+func TestAttemptScriptEscalation(t *testing.T) {
+	r, mr := newTestLimiter(t, Backoff{OffenseWindow: 24 * time.Hour, LockoutMultiplier: 2, MaxLockout: 25 * time.Minute})
+	counters := []string{"counter"}
+	limits := []RateLimit{{MaxAttempts: 1, Window: 15 * time.Minute, Lockout: 10 * time.Minute}}
+
+	lockout := func(want time.Duration) {
+		t.Helper()
+		attempt(t, r, counters, limits, []int64{0, 0, 0})
+		attempt(t, r, counters, limits, []int64{1, want.Milliseconds(), 0})
+		mr.FastForward(want)
+	}
+
+	// Every lockout within the offense window doubles the next one, up to the max lockout
+	lockout(10 * time.Minute)
+	lockout(20 * time.Minute)
+	lockout(25 * time.Minute)
+
+	// Once the offense window passes the counter starts over
+	mr.FastForward(24 * time.Hour)
+	lockout(10 * time.Minute)
+}
+
+func TestAttemptScriptRejectedNotCharged(t *testing.T) {
+	r, mr := newTestLimiter(t, Backoff{OffenseWindow: 24 * time.Hour, LockoutMultiplier: 1})
+	counters := []string{"account", "ip"}
+	limits := []RateLimit{
+		{MaxAttempts: 1, Window: 15 * time.Minute, Lockout: 10 * time.Minute},
+		{MaxAttempts: 5, Window: 15 * time.Minute, Lockout: 10 * time.Minute},
+	}
+
+	attempt(t, r, counters, limits, []int64{0, 0, 0})
+	attempt(t, r, counters, limits, []int64{1, 600000, 0})
+	attempt(t, r, counters, limits, []int64{1, 600000, 0})
+
+	if got, _ := mr.Get("ip"); got != "1" {
+		t.Errorf("ip attempts = %s, want 1: rejected attempts must not be charged", got)
+	}
+	if mr.Exists("ip:offenses") {
+		t.Error("ip counter got an offense for an attempt another counter rejected")
+	}
+}
+
+func TestRefundScript(t *testing.T) {
+	r, mr := newTestLimiter(t, Backoff{})
+	mr.Set("ip", "2")
+	mr.SetTTL("ip", 15*time.Minute)
+
+	if err := refundScript.Run(context.Background(), r.client.Client, []string{"ip", "subnet"}).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := mr.Get("ip"); got != "1" {
+		t.Errorf("ip attempts = %s, want 1", got)
+	}
+	if ttl := mr.TTL("ip"); ttl != 15*time.Minute {
+		t.Errorf("ip window = %v, want it untouched", ttl)
+	}
+	if mr.Exists("subnet") {
+		t.Error("refund created a counter that held no attempt")
+	}
+}
+
+func TestCounterState(t *testing.T) {
+	r := &RateLimiterRepository{cfg: RateLimiterConfig{
+		Backoff: Backoff{LockoutMultiplier: 2, MaxLockout: time.Hour},
+	}}
+	counter := LimiterCounter{Dimension: "pin", limit: RateLimit{MaxAttempts: 5, Window: 15 * time.Minute, Lockout: 10 * time.Minute}}
+
+	tests := []struct {
+		name     string
+		attempts int
+		offenses int
+		ttl      time.Duration
+		delay    time.Duration
+		want     CounterState
+	}{
+		{
+			name:     "below the threshold waits for the delay",
+			attempts: 2,
+			ttl:      10 * time.Minute,
+			delay:    2 * time.Second,
+			want:     CounterState{Attempts: 2, RetryAfter: 2 * time.Second, ResetIn: 10 * time.Minute},
+		},
+		{
+			name:     "at the threshold is not locked yet",
+			attempts: 5,
+			ttl:      10 * time.Minute,
+			want:     CounterState{Attempts: 5, AtThreshold: true, NextLockout: 10 * time.Minute, ResetIn: 10 * time.Minute},
+		},
+		{
+			name:     "next lockout escalates with remembered offenses",
+			attempts: 5,
+			offenses: 2,
+			ttl:      10 * time.Minute,
+			want:     CounterState{Attempts: 5, AtThreshold: true, NextLockout: 40 * time.Minute, ResetIn: 10 * time.Minute, Offenses: 2},
+		},
+		{
+			name:     "next lockout is capped",
+			attempts: 5,
+			offenses: 5,
+			ttl:      10 * time.Minute,
+			want:     CounterState{Attempts: 5, AtThreshold: true, NextLockout: time.Hour, ResetIn: 10 * time.Minute, Offenses: 5},
+		},
+		{
+			name:     "past the threshold is locked until the count expires",
+			attempts: 6,
+			offenses: 1,
+			ttl:      7 * time.Minute,
+			want:     CounterState{Attempts: 6, Locked: true, RetryAfter: 7 * time.Minute, ResetIn: 7 * time.Minute, Offenses: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.LimiterCounter = counter
+			if got := r.counterState(counter, tt.attempts, tt.offenses, tt.ttl, tt.delay); got != tt.want {
+				t.Errorf("counterState() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/saifoelloh/ranger/internal/constant"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+// SSONonceRepository keeps the single-use nonces issued for SSO ID tokens
+type SSONonceRepository struct {
+	client *RedisClient
+}
+
+func NewSSONonceRepository(client *RedisClient) *SSONonceRepository {
+	return &SSONonceRepository{client: client}
+}
+
+func ssoNonceKey(digest string) string {
+	return fmt.Sprintf("%s:%s", constant.SSONonce, digest)
+}
+
+// Save registers the digest of an issued nonce so it can be redeemed exactly once
+func (r *SSONonceRepository) Save(ctx context.Context, digest string, ttl time.Duration) error {
+	if err := r.client.Client.Set(ctx, ssoNonceKey(digest), 1, ttl).Err(); err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("SSONonceRepository"),
+			errors.WithLocation("Save.Set"),
+			errors.WithMessage("failed to store sso nonce"),
+			errors.WithErrorCode("redis/set-sso-nonce-failed"),
+		)
+	}
+
+	return nil
+}
+
+// Consume redeems whichever of the digests is stored, reporting whether one was
+func (r *SSONonceRepository) Consume(ctx context.Context, digests ...string) (bool, error) {
+	keys := make([]string, len(digests))
+	for i, digest := range digests {
+		keys[i] = ssoNonceKey(digest)
+	}
+
+	deleted, err := r.client.Client.Del(ctx, keys...).Result()
+	if err != nil {
+		return false, errors.InternalServerError(
+			errors.WithScope("SSONonceRepository"),
+			errors.WithLocation("Consume.Del"),
+			errors.WithMessage("failed to redeem sso nonce"),
+			errors.WithErrorCode("redis/del-sso-nonce-failed"),
+		)
+	}
+
+	return deleted > 0, nil
+}
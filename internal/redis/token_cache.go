@@ -2,16 +2,21 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/saifoelloh/ranger/internal/dto"
 	"github.com/saifoelloh/ranger/pkg/errors"
 )
 
 const (
-	AccessTokenKey   = "token:access:%s" // %s = userID
-	UserIDByTokenKey = "token:user:%s"   // %s = accessToken
+	// token:access:%s held a string before, a new name keeps SADD off those keys until they expire
+	AccessTokenKey   = "token:access-set:%s"    // %s = userID, set of live access tokens
+	SessionTokenKey  = "token:session:%s"       // %s = sessionID, set of live access tokens
+	UserIDByTokenKey = "token:user:%s"          // %s = accessToken
+	IntrospectionKey = "token:introspection:%s" // %s = accessToken, cached introspection result
 )
 
 type TokenRepository struct {
@@ -22,12 +27,16 @@ func NewTokenRepository(client *RedisClient) *TokenRepository {
 	return &TokenRepository{client: client}
 }
 
-func (r *TokenRepository) SetAccessToken(ctx context.Context, userID, accessToken string, ttl time.Duration) error {
+func (r *TokenRepository) SetAccessToken(ctx context.Context, userID, sessionID, accessToken string, ttl time.Duration) error {
 	accessTokenKey := fmt.Sprintf(AccessTokenKey, userID)
+	sessionTokenKey := fmt.Sprintf(SessionTokenKey, sessionID)
 	userIDKey := fmt.Sprintf(UserIDByTokenKey, accessToken)
 
 	pipe := r.client.Client.Pipeline()
-	pipe.Set(ctx, accessTokenKey, userID, ttl)
+	pipe.SAdd(ctx, accessTokenKey, accessToken)
+	pipe.Expire(ctx, accessTokenKey, ttl)
+	pipe.SAdd(ctx, sessionTokenKey, accessToken)
+	pipe.Expire(ctx, sessionTokenKey, ttl)
 	pipe.Set(ctx, userIDKey, userID, ttl)
 
 	_, err := pipe.Exec(ctx)
@@ -64,3 +73,111 @@ func (r *TokenRepository) GetUserIDFromToken(ctx context.Context, accessToken st
 	}
 	return userID, nil
 }
+
+// DeleteSessionTokens removes every access token issued for a single session
+func (r *TokenRepository) DeleteSessionTokens(ctx context.Context, userID, sessionID string) error {
+	sessionTokenKey := fmt.Sprintf(SessionTokenKey, sessionID)
+	tokens, err := r.client.Client.SMembers(ctx, sessionTokenKey).Result()
+	if err != nil && err != redis.Nil {
+		return errors.InternalServerError(
+			errors.WithScope("TokenRepository"),
+			errors.WithLocation("DeleteSessionTokens.SMembers"),
+			errors.WithMessage("failed to read session tokens from Redis"),
+			errors.WithErrorCode("redis/get-token-failed"),
+		)
+	}
+
+	pipe := r.client.Client.Pipeline()
+	for _, token := range tokens {
+		pipe.Del(ctx, fmt.Sprintf(UserIDByTokenKey, token), fmt.Sprintf(IntrospectionKey, token))
+		pipe.SRem(ctx, fmt.Sprintf(AccessTokenKey, userID), token)
+	}
+	pipe.Del(ctx, sessionTokenKey)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("TokenRepository"),
+			errors.WithLocation("DeleteSessionTokens.Exec"),
+			errors.WithMessage("failed to delete session tokens from Redis"),
+			errors.WithErrorCode("redis/del-token-failed"),
+		)
+	}
+
+	return nil
+}
+
+// DeleteUserTokens removes every access token issued to a user across all sessions
+func (r *TokenRepository) DeleteUserTokens(ctx context.Context, userID string) error {
+	accessTokenKey := fmt.Sprintf(AccessTokenKey, userID)
+	tokens, err := r.client.Client.SMembers(ctx, accessTokenKey).Result()
+	if err != nil && err != redis.Nil {
+		return errors.InternalServerError(
+			errors.WithScope("TokenRepository"),
+			errors.WithLocation("DeleteUserTokens.SMembers"),
+			errors.WithMessage("failed to read user tokens from Redis"),
+			errors.WithErrorCode("redis/get-token-failed"),
+		)
+	}
+
+	pipe := r.client.Client.Pipeline()
+	for _, token := range tokens {
+		pipe.Del(ctx, fmt.Sprintf(UserIDByTokenKey, token), fmt.Sprintf(IntrospectionKey, token))
+	}
+	pipe.Del(ctx, accessTokenKey)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("TokenRepository"),
+			errors.WithLocation("DeleteUserTokens.Exec"),
+			errors.WithMessage("failed to delete user tokens from Redis"),
+			errors.WithErrorCode("redis/del-token-failed"),
+		)
+	}
+
+	return nil
+}
+
+// GetIntrospection returns a cached introspection result, found is false on a cache miss
+func (r *TokenRepository) GetIntrospection(ctx context.Context, accessToken string) (*dto.IntrospectionResponse, bool, error) {
+	data, err := r.client.Client.Get(ctx, fmt.Sprintf(IntrospectionKey, accessToken)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false, nil
+		}
+		return nil, false, errors.InternalServerError(
+			errors.WithScope("TokenRepository"),
+			errors.WithLocation("GetIntrospection.Get"),
+			errors.WithMessage("failed to read introspection result from Redis"),
+			errors.WithErrorCode("redis/get-token-failed"),
+		)
+	}
+
+	var resp dto.IntrospectionResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, false, nil
+	}
+	return &resp, true, nil
+}
+
+func (r *TokenRepository) SetIntrospection(ctx context.Context, accessToken string, resp *dto.IntrospectionResponse, ttl time.Duration) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("TokenRepository"),
+			errors.WithLocation("SetIntrospection.Marshal"),
+			errors.WithMessage("failed to encode introspection result"),
+			errors.WithErrorCode("redis/set-token-failed"),
+		)
+	}
+
+	if err := r.client.Client.Set(ctx, fmt.Sprintf(IntrospectionKey, accessToken), data, ttl).Err(); err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("TokenRepository"),
+			errors.WithLocation("SetIntrospection.Set"),
+			errors.WithMessage("failed to cache introspection result in Redis"),
+			errors.WithErrorCode("redis/set-token-failed"),
+		)
+	}
+
+	return nil
+}
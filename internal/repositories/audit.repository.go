@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/saifoelloh/ranger/internal/model"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type AuditRepository struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Create records an administrative action in the audit trail
+func (r *AuditRepository) Create(log *model.AuditLog) error {
+	if log.ID == "" {
+		log.ID = uuid.New().String()
+	}
+
+	query := `
+		INSERT INTO "AuditLogs" (id, actor_id, actor_role, action, target_type, target_id, reason, detail, ip, "createdAt")
+		VALUES (:id, :actor_id, :actor_role, :action, :target_type, :target_id, :reason, :detail, :ip, now())`
+	_, err := r.db.NamedExec(query, log)
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("AuditRepository"),
+			errors.WithLocation("Create"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("audit/create-failed"),
+		)
+	}
+
+	return nil
+}
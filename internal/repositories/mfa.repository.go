@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/saifoelloh/ranger/internal/model"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type MFARepository struct {
+	db *sqlx.DB
+}
+
+func NewMFARepository(db *sqlx.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+func (r *MFARepository) FindTOTPByUserID(userID string) (*model.UserTotp, error) {
+	var totp model.UserTotp
+
+	query := `
+		SELECT user_id, secret, pending_secret, enabled, last_used_step, "createdAt", "updatedAt"
+		FROM "UserTotps"
+		WHERE user_id = $1`
+	err := r.db.Get(&totp, query, userID)
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound(
+			errors.WithScope("MFARepository"),
+			errors.WithLocation("FindTOTPByUserID"),
+			errors.WithMessage("totp not enrolled"),
+			errors.WithErrorCode("mfa/not-enrolled"),
+		)
+	}
+	if err != nil {
+		return nil, errors.InternalServerError(
+			errors.WithScope("MFARepository"),
+			errors.WithLocation("FindTOTPByUserID"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("mfa/fetch-failed"),
+		)
+	}
+
+	return &totp, nil
+}
+
+// SavePendingTOTP stores a secret awaiting activation. An active secret is left untouched until
+// ActivatePendingTOTP swaps the pending one in, replacing any earlier pending secret.
+func (r *MFARepository) SavePendingTOTP(userID, secret string) error {
+	query := `
+		INSERT INTO "UserTotps" (user_id, secret, pending_secret, enabled, last_used_step, "createdAt", "updatedAt")
+		VALUES ($1, '', $2, false, 0, now(), now())
+		ON CONFLICT (user_id) DO UPDATE
+		SET pending_secret = EXCLUDED.pending_secret, "updatedAt" = now()`
+	_, err := r.db.Exec(query, userID, secret)
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("MFARepository"),
+			errors.WithLocation("SavePendingTOTP"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("mfa/save-failed"),
+		)
+	}
+
+	return nil
+}
+
+// ActivatePendingTOTP makes the pending secret the active one and records the step of the code
+// that confirmed it. It reports false when the pending secret changed in the meantime.
+func (r *MFARepository) ActivatePendingTOTP(userID, pendingSecret string, step int64) (bool, error) {
+	query := `
+		UPDATE "UserTotps"
+		SET secret = pending_secret, pending_secret = NULL, enabled = true,
+			last_used_step = GREATEST(last_used_step, $3), "updatedAt" = now()
+		WHERE user_id = $1 AND pending_secret = $2`
+	result, err := r.db.Exec(query, userID, pendingSecret, step)
+	if err != nil {
+		return false, errors.InternalServerError(
+			errors.WithScope("MFARepository"),
+			errors.WithLocation("ActivatePendingTOTP"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("mfa/save-failed"),
+		)
+	}
+
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// MarkTOTPStepUsed records the time step of an accepted code. It reports false when that
+// step (or a later one) was already used, which rejects replayed codes.
+func (r *MFARepository) MarkTOTPStepUsed(userID string, step int64) (bool, error) {
+	query := `UPDATE "UserTotps" SET last_used_step = $2, "updatedAt" = now() WHERE user_id = $1 AND last_used_step < $2`
+	result, err := r.db.Exec(query, userID, step)
+	if err != nil {
+		return false, errors.InternalServerError(
+			errors.WithScope("MFARepository"),
+			errors.WithLocation("MarkTOTPStepUsed"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("mfa/save-failed"),
+		)
+	}
+
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// DeleteTOTP removes the user's TOTP secret and recovery codes
+func (r *MFARepository) DeleteTOTP(userID string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("MFARepository"),
+			errors.WithLocation("DeleteTOTP.Begin"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("mfa/delete-failed"),
+		)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM "RecoveryCodes" WHERE user_id = $1`, userID); err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("MFARepository"),
+			errors.WithLocation("DeleteTOTP.RecoveryCodes"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("mfa/delete-failed"),
+		)
+	}
+	if _, err := tx.Exec(`DELETE FROM "UserTotps" WHERE user_id = $1`, userID); err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("MFARepository"),
+			errors.WithLocation("DeleteTOTP.UserTotps"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("mfa/delete-failed"),
+		)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("MFARepository"),
+			errors.WithLocation("DeleteTOTP.Commit"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("mfa/delete-failed"),
+		)
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes invalidates every existing recovery code and stores the new hashes
+func (r *MFARepository) ReplaceRecoveryCodes(userID string, codeHashes []string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("MFARepository"),
+			errors.WithLocation("ReplaceRecoveryCodes.Begin"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("mfa/save-failed"),
+		)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM "RecoveryCodes" WHERE user_id = $1`, userID); err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("MFARepository"),
+			errors.WithLocation("ReplaceRecoveryCodes.Delete"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("mfa/save-failed"),
+		)
+	}
+
+	for _, codeHash := range codeHashes {
+		query := `INSERT INTO "RecoveryCodes" (id, user_id, code_hash, "createdAt") VALUES ($1, $2, $3, now())`
+		if _, err := tx.Exec(query, uuid.New().String(), userID, codeHash); err != nil {
+			return errors.InternalServerError(
+				errors.WithScope("MFARepository"),
+				errors.WithLocation("ReplaceRecoveryCodes.Insert"),
+				errors.WithDetail(err.Error()),
+				errors.WithErrorCode("mfa/save-failed"),
+			)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("MFARepository"),
+			errors.WithLocation("ReplaceRecoveryCodes.Commit"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("mfa/save-failed"),
+		)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode spends an unused recovery code, reporting whether one matched
+func (r *MFARepository) UseRecoveryCode(userID, codeHash string) (bool, error) {
+	query := `UPDATE "RecoveryCodes" SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	result, err := r.db.Exec(query, userID, codeHash)
+	if err != nil {
+		return false, errors.InternalServerError(
+			errors.WithScope("MFARepository"),
+			errors.WithLocation("UseRecoveryCode"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("mfa/save-failed"),
+		)
+	}
+
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
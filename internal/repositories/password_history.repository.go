@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/saifoelloh/ranger/internal/model"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type PasswordHistoryRepository struct {
+	db *sqlx.DB
+}
+
+func NewPasswordHistoryRepository(db *sqlx.DB) *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{db: db}
+}
+
+func (r *PasswordHistoryRepository) Create(userID, passwordHash string) error {
+	query := `
+		INSERT INTO "PasswordHistories" (id, user_id, password, "createdAt")
+		VALUES ($1, $2, $3, now())`
+	_, err := r.db.Exec(query, uuid.New().String(), userID, passwordHash)
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("PasswordHistoryRepository"),
+			errors.WithLocation("Create"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("password/history-save-failed"),
+		)
+	}
+
+	return nil
+}
+
+// FindRecentByUserID returns the user's most recent previous passwords, newest first
+func (r *PasswordHistoryRepository) FindRecentByUserID(userID string, limit int) ([]model.PasswordHistory, error) {
+	histories := []model.PasswordHistory{}
+	if limit <= 0 {
+		return histories, nil
+	}
+
+	query := `
+		SELECT id, user_id, password, "createdAt"
+		FROM "PasswordHistories"
+		WHERE user_id = $1
+		ORDER BY "createdAt" DESC
+		LIMIT $2`
+	err := r.db.Select(&histories, query, userID, limit)
+	if err != nil {
+		return nil, errors.InternalServerError(
+			errors.WithScope("PasswordHistoryRepository"),
+			errors.WithLocation("FindRecentByUserID"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("password/history-fetch-failed"),
+		)
+	}
+
+	return histories, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/saifoelloh/ranger/internal/model"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type PinRepository struct {
+	db *sqlx.DB
+}
+
+func NewPinRepository(db *sqlx.DB) *PinRepository {
+	return &PinRepository{db: db}
+}
+
+func (r *PinRepository) FindByUserID(userID string) (*model.UserPin, error) {
+	var pin model.UserPin
+
+	query := `
+		SELECT user_id, pin, "createdAt", "updatedAt"
+		FROM "UserPins"
+		WHERE user_id = $1`
+	err := r.db.Get(&pin, query, userID)
+	if err != nil {
+		return nil, errors.NotFound(
+			errors.WithScope("PinRepository"),
+			errors.WithLocation("FindByUserID"),
+			errors.WithMessage("pin has not been set"),
+			errors.WithErrorCode("pin/not-set"),
+		)
+	}
+
+	return &pin, nil
+}
+
+// Create stores the first PIN of a user and reports false when one already exists
+func (r *PinRepository) Create(userID, pinHash string) (bool, error) {
+	query := `
+		INSERT INTO "UserPins" (user_id, pin, "createdAt", "updatedAt")
+		VALUES ($1, $2, now(), now())
+		ON CONFLICT (user_id) DO NOTHING`
+	result, err := r.db.Exec(query, userID, pinHash)
+	if err != nil {
+		return false, errors.InternalServerError(
+			errors.WithScope("PinRepository"),
+			errors.WithLocation("Create"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("pin/save-failed"),
+		)
+	}
+
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+func (r *PinRepository) Update(userID, pinHash string) error {
+	query := `UPDATE "UserPins" SET pin = $2, "updatedAt" = now() WHERE user_id = $1`
+	_, err := r.db.Exec(query, userID, pinHash)
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("PinRepository"),
+			errors.WithLocation("Update"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("pin/save-failed"),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/saifoelloh/ranger/internal/model"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type RefreshTokenRepository struct {
+	db *sqlx.DB
+}
+
+func NewRefreshTokenRepository(db *sqlx.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+func (r *RefreshTokenRepository) Create(token *model.RefreshToken) error {
+	query := `
+		INSERT INTO "RefreshTokens"
+		(id, session_id, user_id, family_id, parent_id, token_hash, revoked, expires_at)
+		VALUES (:id, :session_id, :user_id, :family_id, :parent_id, :token_hash, :revoked, :expires_at)
+	`
+	_, err := r.db.NamedExec(query, token)
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("RefreshTokenRepository"),
+			errors.WithLocation("Create"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("refresh-token/create"),
+		)
+	}
+
+	return nil
+}
+
+func (r *RefreshTokenRepository) FindByTokenHash(tokenHash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+
+	query := `
+		SELECT id, session_id, user_id, family_id, parent_id, token_hash, revoked, used_at, expires_at, "createdAt", "updatedAt"
+		FROM "RefreshTokens"
+		WHERE token_hash = $1`
+	err := r.db.Get(&token, query, tokenHash)
+	if err != nil {
+		return nil, errors.NotFound(
+			errors.WithScope("RefreshTokenRepository"),
+			errors.WithLocation("FindByTokenHash"),
+			errors.WithMessage("refresh token not found"),
+			errors.WithErrorCode("refresh-token/not-found"),
+		)
+	}
+
+	return &token, nil
+}
+
+// MarkUsed records that a refresh token has been rotated. It reports false when the
+// token was already used or revoked, so concurrent refreshes cannot both spend it.
+func (r *RefreshTokenRepository) MarkUsed(id string) (bool, error) {
+	query := `UPDATE "RefreshTokens" SET used_at = now(), "updatedAt" = now() WHERE id = $1 AND used_at IS NULL AND revoked = false`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return false, errors.InternalServerError(
+			errors.WithScope("RefreshTokenRepository"),
+			errors.WithLocation("MarkUsed"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("refresh-token/mark-used-failed"),
+		)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.InternalServerError(
+			errors.WithScope("RefreshTokenRepository"),
+			errors.WithLocation("MarkUsed.RowsAffected"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("refresh-token/mark-used-failed"),
+		)
+	}
+
+	return affected > 0, nil
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(familyID string) error {
+	query := `UPDATE "RefreshTokens" SET revoked = true, "updatedAt" = now() WHERE family_id = $1 AND revoked = false`
+	_, err := r.db.Exec(query, familyID)
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("RefreshTokenRepository"),
+			errors.WithLocation("RevokeFamily"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("refresh-token/revoke-failed"),
+		)
+	}
+
+	return nil
+}
+
+func (r *RefreshTokenRepository) RevokeBySessionID(sessionID string) error {
+	query := `UPDATE "RefreshTokens" SET revoked = true, "updatedAt" = now() WHERE session_id = $1 AND revoked = false`
+	_, err := r.db.Exec(query, sessionID)
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("RefreshTokenRepository"),
+			errors.WithLocation("RevokeBySessionID"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("refresh-token/revoke-failed"),
+		)
+	}
+
+	return nil
+}
+
+func (r *RefreshTokenRepository) RevokeByUserID(userID string) error {
+	query := `UPDATE "RefreshTokens" SET revoked = true, "updatedAt" = now() WHERE user_id = $1 AND revoked = false`
+	_, err := r.db.Exec(query, userID)
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("RefreshTokenRepository"),
+			errors.WithLocation("RevokeByUserID"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("refresh-token/revoke-failed"),
+		)
+	}
+
+	return nil
+}
@@ -17,8 +17,8 @@ func NewSessionRepository(db *sqlx.DB) *SessionRepository {
 func (r *SessionRepository) CreateSession(session *model.Session) error {
 	query := `
 		INSERT INTO "Sessions"
-		(id, user_id, client_version, device, mac_address, public_key, active, ip, user_agent)
-		VALUES (:id, :user_id, :client_version, :device, :mac_address, :public_key, :active, :ip, :user_agent)
+		(id, user_id, client_version, device, mac_address, public_key, active, ip, user_agent, location, "createdAt")
+		VALUES (:id, :user_id, :client_version, :device, :mac_address, :public_key, :active, :ip, :user_agent, :location, :createdAt)
 	`
 	_, err := r.db.NamedExec(query, session)
 	if err != nil {
@@ -33,11 +33,12 @@ func (r *SessionRepository) CreateSession(session *model.Session) error {
 	return nil
 }
 
-func (r *SessionRepository) DeactivateSessionsByUserID(userID string) error {
+// DeactivateSessionsByUserID deactivates every active session of a user and returns how many were closed
+func (r *SessionRepository) DeactivateSessionsByUserID(userID string) (int64, error) {
 	query := `UPDATE "Sessions" SET active = false WHERE user_id = $1 AND active = true`
-	_, err := r.db.Exec(query, userID)
+	result, err := r.db.Exec(query, userID)
 	if err != nil {
-		return errors.InternalServerError(
+		return 0, errors.InternalServerError(
 			errors.WithScope("SessionRepository"),
 			errors.WithLocation("DeactivateSessionsByUserID"),
 			errors.WithDetail(err.Error()),
@@ -45,5 +46,102 @@ func (r *SessionRepository) DeactivateSessionsByUserID(userID string) error {
 		)
 	}
 
+	affected, _ := result.RowsAffected()
+	return affected, nil
+}
+
+func (r *SessionRepository) FindByID(id string) (*model.Session, error) {
+	var session model.Session
+
+	query := `
+		SELECT id, user_id, device, mac_address, public_key, active, client_version, ip, user_agent, location, "createdAt", "updatedAt"
+		FROM "Sessions"
+		WHERE id = $1`
+	err := r.db.Get(&session, query, id)
+	if err != nil {
+		return nil, errors.NotFound(
+			errors.WithScope("SessionRepository"),
+			errors.WithLocation("FindByID"),
+			errors.WithMessage("session not found"),
+			errors.WithErrorCode("session/not-found"),
+		)
+	}
+
+	return &session, nil
+}
+
+// DeactivateSession deactivates a single session and returns how many rows were closed (0 or 1)
+func (r *SessionRepository) DeactivateSession(id string) (int64, error) {
+	query := `UPDATE "Sessions" SET active = false WHERE id = $1 AND active = true`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return 0, errors.InternalServerError(
+			errors.WithScope("SessionRepository"),
+			errors.WithLocation("DeactivateSession"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("session/deactivation-failed"),
+		)
+	}
+
+	affected, _ := result.RowsAffected()
+	return affected, nil
+}
+
+func (r *SessionRepository) FindActiveByUserID(userID string) ([]model.Session, error) {
+	sessions := []model.Session{}
+
+	query := `
+		SELECT id, user_id, device, mac_address, public_key, active, client_version, ip, user_agent, location, "createdAt", "updatedAt"
+		FROM "Sessions"
+		WHERE user_id = $1 AND active = true
+		ORDER BY "updatedAt" DESC`
+	err := r.db.Select(&sessions, query, userID)
+	if err != nil {
+		return nil, errors.InternalServerError(
+			errors.WithScope("SessionRepository"),
+			errors.WithLocation("FindActiveByUserID"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("session/list-failed"),
+		)
+	}
+
+	return sessions, nil
+}
+
+// FindMacAddressesByUserID lists the distinct device addresses the user ever signed in from
+func (r *SessionRepository) FindMacAddressesByUserID(userID string) ([]string, error) {
+	addresses := []string{}
+
+	query := `
+		SELECT DISTINCT mac_address
+		FROM "Sessions"
+		WHERE user_id = $1 AND mac_address <> ''
+		ORDER BY mac_address`
+	err := r.db.Select(&addresses, query, userID)
+	if err != nil {
+		return nil, errors.InternalServerError(
+			errors.WithScope("SessionRepository"),
+			errors.WithLocation("FindMacAddressesByUserID"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("session/list-failed"),
+		)
+	}
+
+	return addresses, nil
+}
+
+// Touch records activity on a session so it can be reported as last seen
+func (r *SessionRepository) Touch(id string) error {
+	query := `UPDATE "Sessions" SET "updatedAt" = now() WHERE id = $1`
+	_, err := r.db.Exec(query, id)
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("SessionRepository"),
+			errors.WithLocation("Touch"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("session/touch-failed"),
+		)
+	}
+
 	return nil
 }
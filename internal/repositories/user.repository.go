@@ -1,7 +1,12 @@
 package repository
 
 import (
+	"database/sql"
+	"fmt"
+	"time"
+
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/saifoelloh/ranger/internal/constant"
 	"github.com/saifoelloh/ranger/internal/model"
 	"github.com/saifoelloh/ranger/pkg/errors"
@@ -15,11 +20,33 @@ func NewUserRepository(db *sqlx.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+func (r *UserRepository) FindByID(id string) (*model.User, error) {
+	var user model.User
+
+	query := `
+		SELECT id, first_name, last_name, email, phone_number, investor_type, role, password,
+			COALESCE(last_change_password, to_timestamp(0)) AS last_change_password
+		FROM "Users"
+		WHERE id = $1 AND is_deleted = false`
+	err := r.db.Get(&user, query, id)
+
+	if err != nil {
+		return nil, errors.NotFound(
+			errors.WithScope("UserRepository"),
+			errors.WithLocation("FindByID"),
+			errors.WithMessage("user not found"),
+			errors.WithErrorCode("user/not-found"),
+		)
+	}
+
+	return &user, nil
+}
+
 func (r *UserRepository) FindByEmail(email string) (*model.User, error) {
 	var user model.User
 
 	query := `
-		SELECT id, first_name, last_name, email, phone_number, investor_type, password
+		SELECT id, first_name, last_name, email, phone_number, investor_type, role, password
 		FROM "Users"
 		WHERE email_hash = $1`
 	err := r.db.Get(&user, query, email)
@@ -36,23 +63,57 @@ func (r *UserRepository) FindByEmail(email string) (*model.User, error) {
 	return &user, nil
 }
 
+func (r *UserRepository) FindByPhoneHash(phoneHash string) (*model.User, error) {
+	var user model.User
+
+	query := `
+		SELECT id, first_name, last_name, email, phone_number, investor_type, role
+		FROM "Users"
+		WHERE phone_number_hash = $1 AND is_deleted = false`
+	err := r.db.Get(&user, query, phoneHash)
+
+	if err != nil {
+		return nil, errors.NotFound(
+			errors.WithScope("UserRepository"),
+			errors.WithLocation("FindByPhoneHash"),
+			errors.WithMessage("user not found"),
+			errors.WithErrorCode("user/not-found"),
+		)
+	}
+
+	return &user, nil
+}
+
 func (r *UserRepository) FindBySSOID(ssoID string, ssoPlatform constant.SSOPlatform) (*model.User, error) {
 	var user model.User
 	var query string
 
-	if ssoPlatform == constant.SSOPlatformApple {
+	switch ssoPlatform {
+	case constant.SSOPlatformApple:
+		query = `
+			SELECT id, first_name, last_name, email, phone_number, investor_type, role, sso_sign_option, apple_sso_id
+			FROM "Users"
+			WHERE apple_sso_id = $1 AND is_deleted = false`
+	case constant.SSOPlatformGoogle:
 		query = `
-			SELECT id, first_name, last_name, email, phone_number, investor_type, sso_sign_option, apple_sso_id
+			SELECT id, first_name, last_name, email, phone_number, investor_type, role, sso_sign_option, google_sso_id
 			FROM "Users"
-			WHERE apple_sso_id = $1 and sso_sign_option = $2`
-	} else if ssoPlatform == constant.SSOPlatformGoogle {
+			WHERE google_sso_id = $1 AND is_deleted = false`
+	case constant.SSOPlatformFacebook:
 		query = `
-			SELECT id, first_name, last_name, email, phone_number, investor_type, sso_sign_option, google_sso_id
+			SELECT id, first_name, last_name, email, phone_number, investor_type, role, sso_sign_option, facebook_sso_id
 			FROM "Users"
-			WHERE google_sso_id = $1 and sso_sign_option = $2`
+			WHERE facebook_sso_id = $1 AND is_deleted = false`
+	default:
+		return nil, errors.BadRequest(
+			errors.WithScope("UserRepository"),
+			errors.WithLocation("FindBySSOID.Platform"),
+			errors.WithMessage("unsupported sso_platform"),
+			errors.WithErrorCode("auth/unsupported-sso-platform"),
+		)
 	}
-	err := r.db.Get(&user, query, ssoID, ssoPlatform)
-	if err != nil {
+	err := r.db.Get(&user, query, ssoID)
+	if err == sql.ErrNoRows {
 		return nil, errors.NotFound(
 			errors.WithScope("UserRepository"),
 			errors.WithLocation("FindBySSOID"),
@@ -60,6 +121,245 @@ func (r *UserRepository) FindBySSOID(ssoID string, ssoPlatform constant.SSOPlatf
 			errors.WithErrorCode("user/not-found"),
 		)
 	}
+	if err != nil {
+		return nil, errors.InternalServerError(
+			errors.WithScope("UserRepository"),
+			errors.WithLocation("FindBySSOID"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("user/fetch-failed"),
+		)
+	}
+
+	return &user, nil
+}
+
+// UpdatePassword stores a new bcrypt hash and records when the password changed
+func (r *UserRepository) UpdatePassword(userID, passwordHash string) error {
+	query := `UPDATE "Users" SET password = $2, last_change_password = now(), "updatedAt" = now() WHERE id = $1`
+	_, err := r.db.Exec(query, userID, passwordHash)
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("UserRepository"),
+			errors.WithLocation("UpdatePassword"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("user/update-password-failed"),
+		)
+	}
+
+	return nil
+}
+
+// ExistsByEmailOrPhoneHash reports which of the given hashes already belong to a user
+func (r *UserRepository) ExistsByEmailOrPhoneHash(emailHash, phoneHash string) (emailTaken, phoneTaken bool, err error) {
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM "Users" WHERE email_hash = $1 AND is_deleted = false),
+			$2 <> '' AND EXISTS (SELECT 1 FROM "Users" WHERE phone_number_hash = $2 AND is_deleted = false)`
+	err = r.db.QueryRow(query, emailHash, phoneHash).Scan(&emailTaken, &phoneTaken)
+	if err != nil {
+		return false, false, errors.InternalServerError(
+			errors.WithScope("UserRepository"),
+			errors.WithLocation("ExistsByEmailOrPhoneHash"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("user/fetch-failed"),
+		)
+	}
+
+	return emailTaken, phoneTaken, nil
+}
+
+// Create inserts a new user. A unique violation on email or phone is reported as a conflict.
+func (r *UserRepository) Create(user *model.User) error {
+	query := `
+		INSERT INTO "Users" (
+			id, first_name, last_name, email, email_hash, email_verification_code, email_verification_expires_at, email_verified,
+			password, phone_number, phone_number_hash, phone_number_verified, last_change_password,
+			role, know_from, is_deleted, "createdAt", "updatedAt"
+		) VALUES (
+			:id, :first_name, :last_name, :email, :email_hash, :email_verification_code, :email_verification_expires_at, :email_verified,
+			:password, :phone_number, :phone_number_hash, :phone_number_verified, now(),
+			:role, :know_from, false, now(), now()
+		)`
+	_, err := r.db.NamedExec(query, user)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return errors.Conflict(
+				errors.WithScope("UserRepository"),
+				errors.WithLocation("Create.UniqueViolation"),
+				errors.WithMessage("email or phone number is already registered"),
+				errors.WithErrorCode("user/already-exists"),
+			)
+		}
+		return errors.InternalServerError(
+			errors.WithScope("UserRepository"),
+			errors.WithLocation("Create"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("user/create-failed"),
+		)
+	}
+
+	return nil
+}
+
+func (r *UserRepository) FindEmailVerification(emailHash string) (*model.User, error) {
+	var user model.User
+
+	query := `
+		SELECT id, first_name, email, COALESCE(email_verification_code, '') AS email_verification_code,
+			email_verification_expires_at, email_verified
+		FROM "Users"
+		WHERE email_hash = $1 AND is_deleted = false`
+	err := r.db.Get(&user, query, emailHash)
+	if err != nil {
+		return nil, errors.NotFound(
+			errors.WithScope("UserRepository"),
+			errors.WithLocation("FindEmailVerification"),
+			errors.WithMessage("user not found"),
+			errors.WithErrorCode("user/not-found"),
+		)
+	}
+
+	return &user, nil
+}
+
+// SetEmailVerificationCode replaces the verification code of a user who has not verified yet
+func (r *UserRepository) SetEmailVerificationCode(userID, codeHash string, expiresAt time.Time) error {
+	query := `
+		UPDATE "Users"
+		SET email_verification_code = $2, email_verification_expires_at = $3, "updatedAt" = now()
+		WHERE id = $1 AND email_verified = false`
+	_, err := r.db.Exec(query, userID, codeHash, expiresAt)
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("UserRepository"),
+			errors.WithLocation("SetEmailVerificationCode"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("user/update-failed"),
+		)
+	}
+
+	return nil
+}
+
+// MarkEmailVerified flips email_verified and clears the verification code
+func (r *UserRepository) MarkEmailVerified(userID string) error {
+	query := `
+		UPDATE "Users"
+		SET email_verified = true, email_verification_code = NULL, email_verification_expires_at = NULL, "updatedAt" = now()
+		WHERE id = $1`
+	_, err := r.db.Exec(query, userID)
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("UserRepository"),
+			errors.WithLocation("MarkEmailVerified"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("user/update-failed"),
+		)
+	}
+
+	return nil
+}
+
+// ssoColumn maps a platform to the column holding its sso id
+func ssoColumn(ssoPlatform constant.SSOPlatform) (string, error) {
+	switch ssoPlatform {
+	case constant.SSOPlatformApple:
+		return "apple_sso_id", nil
+	case constant.SSOPlatformGoogle:
+		return "google_sso_id", nil
+	case constant.SSOPlatformFacebook:
+		return "facebook_sso_id", nil
+	}
+
+	return "", errors.BadRequest(
+		errors.WithScope("UserRepository"),
+		errors.WithLocation("ssoColumn"),
+		errors.WithMessage("unsupported sso_platform"),
+		errors.WithErrorCode("auth/unsupported-sso-platform"),
+	)
+}
+
+// FindLoginMethods loads the columns that decide how a user can sign in
+func (r *UserRepository) FindLoginMethods(userID string) (*model.User, error) {
+	var user model.User
+
+	query := `
+		SELECT id, email_hash, password, phone_number_hash, phone_number_verified, sso_sign_option,
+			google_sso_id, apple_sso_id, facebook_sso_id
+		FROM "Users"
+		WHERE id = $1 AND is_deleted = false`
+	err := r.db.Get(&user, query, userID)
+	if err != nil {
+		return nil, errors.NotFound(
+			errors.WithScope("UserRepository"),
+			errors.WithLocation("FindLoginMethods"),
+			errors.WithMessage("user not found"),
+			errors.WithErrorCode("user/not-found"),
+		)
+	}
 
 	return &user, nil
 }
+
+// LinkSSO stores the sso id of a platform on the user, the first linked platform becomes the sign option
+func (r *UserRepository) LinkSSO(userID string, ssoPlatform constant.SSOPlatform, ssoID string) error {
+	column, err := ssoColumn(ssoPlatform)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE "Users"
+		SET %s = $2, sso_sign_option = COALESCE(sso_sign_option, $3), "updatedAt" = now()
+		WHERE id = $1`, column)
+	_, err = r.db.Exec(query, userID, ssoID, ssoPlatform)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return errors.Conflict(
+				errors.WithScope("UserRepository"),
+				errors.WithLocation("LinkSSO.UniqueViolation"),
+				errors.WithMessage("this sso account is linked to another user"),
+				errors.WithErrorCode("auth/sso-already-linked"),
+			)
+		}
+		return errors.InternalServerError(
+			errors.WithScope("UserRepository"),
+			errors.WithLocation("LinkSSO"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("user/update-failed"),
+		)
+	}
+
+	return nil
+}
+
+// UnlinkSSO clears the sso id of a platform and moves the sign option to another linked platform
+func (r *UserRepository) UnlinkSSO(userID string, ssoPlatform constant.SSOPlatform) error {
+	column, err := ssoColumn(ssoPlatform)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE "Users"
+		SET %[1]s = NULL,
+			sso_sign_option = CASE
+				WHEN sso_sign_option IS DISTINCT FROM $2 THEN sso_sign_option
+				WHEN '%[1]s' <> 'google_sso_id' AND google_sso_id IS NOT NULL THEN 'GOOGLE'
+				WHEN '%[1]s' <> 'apple_sso_id' AND apple_sso_id IS NOT NULL THEN 'APPLE'
+				WHEN '%[1]s' <> 'facebook_sso_id' AND facebook_sso_id IS NOT NULL THEN 'FACEBOOK'
+			END,
+			"updatedAt" = now()
+		WHERE id = $1`, column)
+	_, err = r.db.Exec(query, userID, ssoPlatform)
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("UserRepository"),
+			errors.WithLocation("UnlinkSSO"),
+			errors.WithDetail(err.Error()),
+			errors.WithErrorCode("user/update-failed"),
+		)
+	}
+
+	return nil
+}
@@ -3,6 +3,11 @@ package service
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"net"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -10,46 +15,93 @@ import (
 	jwt "github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/saifoelloh/ranger/internal/config"
+	"github.com/saifoelloh/ranger/internal/constant"
 	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/internal/jwk"
 	"github.com/saifoelloh/ranger/internal/model"
+	"github.com/saifoelloh/ranger/internal/rbac"
 	"github.com/saifoelloh/ranger/internal/redis"
 	repository "github.com/saifoelloh/ranger/internal/repositories"
+	"github.com/saifoelloh/ranger/internal/sso"
 	"github.com/saifoelloh/ranger/internal/utils"
 	"github.com/saifoelloh/ranger/pkg/errors"
 )
 
 type AuthService struct {
 	config           config.Config
+	keyManager       *jwk.KeyManager
 	userRepo         *repository.UserRepository
 	sessionRepo      *repository.SessionRepository
+	refreshTokenRepo *repository.RefreshTokenRepository
 	rateLimiterRedis *redis.RateLimiterRepository
 	tokenCacheRedis  *redis.TokenRepository
+	mfaChallenge     *redis.MFAChallengeRepository
+	mfaService       *MFAService
+	ssoVerifiers     sso.Verifiers
+	permissions      rbac.Matrix
 }
 
 func NewAuthService(
 	config config.Config,
+	keyManager *jwk.KeyManager,
 	userRepo *repository.UserRepository,
 	sessionRepo *repository.SessionRepository,
+	refreshTokenRepo *repository.RefreshTokenRepository,
 	rateLimiterRedis *redis.RateLimiterRepository,
 	tokenCacheRedis *redis.TokenRepository,
+	mfaChallenge *redis.MFAChallengeRepository,
+	mfaService *MFAService,
+	ssoVerifiers sso.Verifiers,
+	permissions rbac.Matrix,
 ) *AuthService {
 	return &AuthService{
 		userRepo:         userRepo,
 		sessionRepo:      sessionRepo,
+		refreshTokenRepo: refreshTokenRepo,
 		config:           config,
+		keyManager:       keyManager,
 		rateLimiterRedis: rateLimiterRedis,
 		tokenCacheRedis:  tokenCacheRedis,
+		mfaChallenge:     mfaChallenge,
+		mfaService:       mfaService,
+		ssoVerifiers:     ssoVerifiers,
+		permissions:      permissions,
 	}
 }
 
+// AdminLogin is Login for admin accounts, which additionally requires an enrolled second
+// factor and, when configured, a request from an allowlisted address
+func (s *AuthService) AdminLogin(ctx context.Context, req dto.LoginInput) (*dto.LoginResponse, error) {
+	req.Admin = true
+	return s.login(ctx, req)
+}
+
+// Login signs in investors. Admin accounts must use AdminLogin.
 func (s *AuthService) Login(ctx context.Context, req dto.LoginInput) (*dto.LoginResponse, error) {
-	uniqueLabel := utils.GetUniqueLabel(req.Email, req.SSOID)
-	s.rateLimiterRedis.IsAllowed(ctx, uniqueLabel)
+	req.Admin = false
+	return s.login(ctx, req)
+}
+
+func (s *AuthService) login(ctx context.Context, req dto.LoginInput) (*dto.LoginResponse, error) {
+	// The sso id is only ever taken from a verified provider token
+	req.SSOID = nil
+	if req.SSOPlatform != nil && *req.SSOPlatform != "" {
+		identity, err := s.ssoVerifiers.Verify(ctx, *req.SSOPlatform, req.IDToken)
+		if err != nil {
+			return nil, err
+		}
+		req.SSOID = &identity.Subject
+	}
+
+	attempt := loginAttemptOf(req)
+	if err := s.rateLimiterRedis.IsLoginAllowed(ctx, attempt); err != nil {
+		return nil, err
+	}
 
 	var user *model.User
 	var err error
 
-	if req.SSOID != nil && *req.SSOID != "" {
+	if req.SSOID != nil {
 		user, err = s.userRepo.FindBySSOID(*req.SSOID, *req.SSOPlatform)
 		if err != nil {
 			return nil, errors.Unauthorized(
@@ -59,10 +111,8 @@ func (s *AuthService) Login(ctx context.Context, req dto.LoginInput) (*dto.Login
 				errors.WithErrorCode("auth/invalid-credentials"),
 			)
 		}
-	}
-
-	if req.Email != nil && *req.Email != "" {
-		hashedEmail := utils.CryptoHash(*req.Email)
+	} else if req.Email != nil && *req.Email != "" {
+		hashedEmail := utils.CryptoHash(utils.NormalizeEmail(*req.Email))
 		user, err = s.userRepo.FindByEmail(hashedEmail)
 		if err != nil {
 			return nil, errors.Unauthorized(
@@ -72,6 +122,19 @@ func (s *AuthService) Login(ctx context.Context, req dto.LoginInput) (*dto.Login
 				errors.WithErrorCode("auth/invalid-credentials"),
 			)
 		}
+
+		password := ""
+		if req.Password != nil {
+			password = *req.Password
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password.String), []byte(password)); password == "" || err != nil {
+			return nil, errors.Unauthorized(
+				errors.WithScope("AuthService"),
+				errors.WithLocation("Login.ComparePassword"),
+				errors.WithMessage("invalid email or password"),
+				errors.WithErrorCode("auth/invalid-credentials"),
+			)
+		}
 	}
 
 	if user == nil {
@@ -83,28 +146,216 @@ func (s *AuthService) Login(ctx context.Context, req dto.LoginInput) (*dto.Login
 		)
 	}
 
-	if req.Password == nil && req.SSOID == nil {
+	if req.PublicKey != "" {
+		if _, err := utils.ParsePublicKey(req.PublicKey); err != nil {
+			return nil, errors.BadRequest(
+				errors.WithScope("AuthService"),
+				errors.WithLocation("Login.ParsePublicKey"),
+				errors.WithMessage("public_key must be an Ed25519 or ECDSA P-256 public key"),
+				errors.WithErrorCode("auth/invalid-public-key"),
+				errors.WithDetail(err.Error()),
+			)
+		}
+	}
+
+	resp, err := s.completeLogin(ctx, user, req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.rateLimiterRedis.ResetLogin(ctx, attempt)
+
+	return resp, nil
+}
+
+// loginAttemptOf identifies the attempt for the login rate limiter
+func loginAttemptOf(req dto.LoginInput) redis.LoginAttempt {
+	attempt := redis.LoginAttempt{
+		IP:     req.IP,
+		Subnet: utils.IPSubnet(req.IP),
+		Device: strings.ToLower(strings.TrimSpace(req.MacAddress)),
+	}
+
+	if req.SSOID != nil {
+		attempt.Account = ssoLoginAccount(*req.SSOPlatform, *req.SSOID)
+	} else if req.Email != nil && *req.Email != "" {
+		attempt.Account = utils.CryptoHash(utils.NormalizeEmail(*req.Email))
+	}
+
+	return attempt
+}
+
+// ssoLoginAccount is the account identifier the login rate limiter counts sso logins on
+func ssoLoginAccount(platform constant.SSOPlatform, ssoID string) string {
+	return utils.CryptoHash(string(platform) + ":" + ssoID)
+}
+
+// completeLogin runs once the first factor succeeded. Users with a second factor get an
+// MFA challenge token to exchange at LoginMFA, everybody else gets a session right away.
+func (s *AuthService) completeLogin(ctx context.Context, user *model.User, req dto.LoginInput) (*dto.LoginResponse, error) {
+	isAdmin := userTypeOf(user) == constant.UserTypeAdmin
+	if isAdmin && !req.Admin {
+		return nil, errors.Forbidden(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("completeLogin.AdminAccount"),
+			errors.WithMessage("admin accounts must sign in through the admin login"),
+			errors.WithErrorCode("auth/admin-login-required"),
+		)
+	}
+	if !isAdmin && req.Admin {
+		return nil, errors.Forbidden(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("completeLogin.NotAdmin"),
+			errors.WithMessage("this account is not an admin account"),
+			errors.WithErrorCode("auth/not-an-admin"),
+		)
+	}
+	if isAdmin {
+		if err := s.checkAdminIP(req.IP); err != nil {
+			return nil, err
+		}
+	}
+
+	mfaEnabled, err := s.mfaService.IsEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !mfaEnabled && isAdmin {
+		return s.issueMFAEnrollmentToken(user)
+	}
+	if !mfaEnabled {
+		return s.startSession(ctx, user, req, time.Time{})
+	}
+
+	mfaToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, errors.InternalServerError(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("completeLogin.GenerateRandomToken"),
+			errors.WithMessage("failed to generate mfa challenge"),
+			errors.WithErrorCode("auth/token-generation-failed"),
+			errors.WithDetail(err.Error()),
+		)
+	}
+
+	req.Password = nil
+	now := time.Now()
+	pending := dto.PendingMFALogin{UserID: user.ID, Input: req, CreatedAt: now}
+	if err := s.mfaChallenge.Save(ctx, utils.CryptoHash(mfaToken), pending, s.config.MFAChallengeExpiry); err != nil {
+		return nil, err
+	}
+
+	expiresAt := now.Add(s.config.MFAChallengeExpiry)
+	return &dto.LoginResponse{
+		MFARequired:  true,
+		MFAToken:     mfaToken,
+		MFAExpiresAt: &expiresAt,
+	}, nil
+}
+
+// issueMFAEnrollmentToken lets an admin who passed the first factor but has no second factor
+// yet enroll one, in place of a session
+func (s *AuthService) issueMFAEnrollmentToken(user *model.User) (*dto.LoginResponse, error) {
+	signedToken, scoped, err := s.signScopedToken(constant.TokenTypeMFAEnrollment, dto.AppClaims{
+		UserID:    user.ID,
+		UserType:  string(userTypeOf(user)),
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Version:   dto.ClaimsVersion,
+	}, constant.ScopeMFAEnrollment, s.config.MFAEnrollmentExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LoginResponse{
+		MFAEnrollmentRequired:  true,
+		MFAEnrollmentToken:     signedToken,
+		MFAEnrollmentExpiresAt: &scoped.ExpiresAt.Time,
+	}, nil
+}
+
+// ValidateMFAEnrollmentToken verifies a token issued by issueMFAEnrollmentToken, used from an
+// address the admin allowlist accepts
+func (s *AuthService) ValidateMFAEnrollmentToken(token, ip string) (*dto.AppClaims, error) {
+	claims, err := s.parseScopedToken(token, constant.TokenTypeMFAEnrollment, constant.ScopeMFAEnrollment)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkAdminIP(ip); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// LoginMFA finishes a login that is waiting for its second factor
+func (s *AuthService) LoginMFA(ctx context.Context, req dto.MFALoginRequest) (*dto.LoginResponse, error) {
+	tokenHash := utils.CryptoHash(req.MFAToken)
+	pending, err := s.mfaChallenge.Get(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaService.Verify(ctx, pending.UserID, req.Code); err != nil {
+		return nil, err
+	}
+
+	deleted, err := s.mfaChallenge.Delete(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if !deleted {
+		return nil, errors.Unauthorized(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("LoginMFA.Delete"),
+			errors.WithMessage("mfa challenge not found or expired"),
+			errors.WithErrorCode("auth/mfa-challenge-invalid"),
+		)
+	}
+
+	user, err := s.userRepo.FindByID(pending.UserID)
+	if err != nil {
 		return nil, errors.Unauthorized(
 			errors.WithScope("AuthService"),
-			errors.WithLocation("Login.NoUserFound"),
-			errors.WithMessage("no user found for given credentials"),
+			errors.WithLocation("LoginMFA.FindUser"),
+			errors.WithMessage("user no longer exists"),
 			errors.WithErrorCode("auth/user-not-found"),
 		)
-	} else if req.Password != nil && *req.Password != "" {
-		if err := bcrypt.CompareHashAndPassword([]byte(user.Password.String), []byte(*req.Password)); err != nil {
-			return nil, errors.Unauthorized(
-				errors.WithScope("AuthService"),
-				errors.WithLocation("Login.ComparePassword"),
-				errors.WithMessage("invalid email or password"),
-				errors.WithErrorCode("auth/invalid-credentials"),
-			)
+	}
+
+	return s.startSession(ctx, user, pending.Input, time.Time{})
+}
+
+// startSession opens a new session for an authenticated user and issues its first token pair.
+// replacedStart is when the session this one replaces was created, zero for a fresh login.
+// Admin sessions keep it so re-logging in without a second factor cannot extend their
+// absolute lifetime.
+func (s *AuthService) startSession(ctx context.Context, user *model.User, req dto.LoginInput, replacedStart time.Time) (*dto.LoginResponse, error) {
+	// Checked here as well so device re-login and finished mfa logins cannot skip it
+	if userTypeOf(user) == constant.UserTypeAdmin {
+		if err := s.checkAdminAccess(user, req.IP); err != nil {
+			return nil, err
 		}
 	}
 
-	if err := s.sessionRepo.DeactivateSessionsByUserID(user.ID); err != nil {
+	if err := s.enforceSessionPolicy(ctx, user, req.DeviceClass); err != nil {
 		return nil, err
 	}
 
+	startedAt := time.Now()
+	if userTypeOf(user) == constant.UserTypeAdmin && !replacedStart.IsZero() {
+		startedAt = replacedStart
+	}
+	absoluteExpiry := startedAt.Add(s.sessionMaxLifetime(user))
+	if time.Now().After(absoluteExpiry) {
+		return nil, errors.Unauthorized(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("startSession.MaxLifetime"),
+			errors.WithMessage("session has reached its maximum lifetime, please log in again"),
+			errors.WithErrorCode("auth/session-expired"),
+		)
+	}
+
 	sessionID := uuid.New().String()
 	session := &model.Session{
 		ID:            sessionID,
@@ -115,32 +366,609 @@ func (s *AuthService) Login(ctx context.Context, req dto.LoginInput) (*dto.Login
 		Active:        true,
 		IP:            sql.NullString{String: req.IP, Valid: true},
 		UserAgent:     sql.NullString{String: req.UserAgent, Valid: true},
-		Location:      sql.NullString{String: req.UserAgent, Valid: true},
+		Location:      sql.NullString{String: req.Location, Valid: req.Location != ""},
 		ClientVersion: req.ClientVersion,
+		CreatedAt:     startedAt,
 	}
 
 	if err := s.sessionRepo.CreateSession(session); err != nil {
 		return nil, err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, dto.AppClaims{
-		UserID:    user.ID,
-		UserType:  "Investor",
-		UserToken: user.InvestorType.String,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		SessionID: sessionID,
+	return s.issueTokens(ctx, user, sessionID, nil, absoluteExpiry)
+}
+
+// checkAdminAccess enforces the admin login policies: a second factor must be enrolled and
+// the request must come from the allowlist when one is configured
+func (s *AuthService) checkAdminAccess(user *model.User, ip string) error {
+	if err := s.checkAdminIP(ip); err != nil {
+		return err
+	}
+
+	mfaEnabled, err := s.mfaService.IsEnabled(user.ID)
+	if err != nil {
+		return err
+	}
+	if !mfaEnabled {
+		return errors.Forbidden(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("checkAdminAccess.MFA"),
+			errors.WithMessage("admin accounts must enroll a second factor before signing in"),
+			errors.WithErrorCode("auth/mfa-enrollment-required"),
+		)
+	}
+
+	return nil
+}
+
+// checkAdminIP rejects admin requests from outside the allowlist when one is configured
+func (s *AuthService) checkAdminIP(ip string) error {
+	if len(s.config.AdminIPAllowlist) == 0 {
+		return nil
+	}
+
+	addr := net.ParseIP(ip)
+	allowed := addr != nil && slices.ContainsFunc(s.config.AdminIPAllowlist, func(network *net.IPNet) bool {
+		return network.Contains(addr)
+	})
+	if !allowed {
+		return errors.Forbidden(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("checkAdminIP.IPAllowlist"),
+			errors.WithMessage("admin access is not allowed from this address"),
+			errors.WithErrorCode("auth/ip-not-allowed"),
+			errors.WithDetail(ip),
+		)
+	}
+
+	return nil
+}
+
+// userTypeOf derives the user type claim from the role, every role except investor is staff
+func userTypeOf(user *model.User) constant.UserType {
+	if user.Role == "" || user.Role == string(constant.RoleInvestor) {
+		return constant.UserTypeInvestor
+	}
+	return constant.UserTypeAdmin
+}
+
+func (s *AuthService) accessTokenExpiry(user *model.User) time.Duration {
+	if userTypeOf(user) == constant.UserTypeAdmin {
+		return s.config.AdminJwtExpiry
+	}
+	return s.config.JwtExpiry
+}
+
+func (s *AuthService) sessionMaxLifetime(user *model.User) time.Duration {
+	if userTypeOf(user) == constant.UserTypeAdmin {
+		return s.config.AdminSessionMaxLifetime
+	}
+	return s.config.RefreshTokenMaxLifetime
+}
+
+// Refresh exchanges a valid refresh token for a new access/refresh pair on the same session
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*dto.LoginResponse, error) {
+	stored, err := s.refreshTokenRepo.FindByTokenHash(utils.CryptoHash(refreshToken))
+	if err != nil {
+		return nil, errors.Unauthorized(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("Refresh.FindByTokenHash"),
+			errors.WithMessage("invalid refresh token"),
+			errors.WithErrorCode("auth/invalid-refresh-token"),
+		)
+	}
+
+	// A token that was already rotated is being replayed: assume it was stolen and
+	// kill the whole family together with the session it belongs to.
+	if stored.UsedAt.Valid {
+		return nil, s.revokeReusedFamily(ctx, stored)
+	}
+
+	if stored.Revoked {
+		return nil, errors.Unauthorized(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("Refresh.Revoked"),
+			errors.WithMessage("refresh token has been revoked"),
+			errors.WithErrorCode("auth/refresh-token-revoked"),
+		)
+	}
+
+	now := time.Now()
+	if now.After(stored.ExpiresAt) {
+		return nil, errors.Unauthorized(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("Refresh.Expired"),
+			errors.WithMessage("refresh token has expired"),
+			errors.WithErrorCode("auth/refresh-token-expired"),
+		)
+	}
+
+	session, err := s.sessionRepo.FindByID(stored.SessionID)
+	if err != nil || !session.Active {
+		return nil, errors.Unauthorized(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("Refresh.SessionInactive"),
+			errors.WithMessage("session is no longer active"),
+			errors.WithErrorCode("auth/session-inactive"),
+		)
+	}
+
+	user, err := s.userRepo.FindByID(stored.UserID)
+	if err != nil {
+		return nil, errors.Unauthorized(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("Refresh.FindUser"),
+			errors.WithMessage("user no longer exists"),
+			errors.WithErrorCode("auth/user-not-found"),
+		)
+	}
+
+	absoluteExpiry := session.CreatedAt.Add(s.sessionMaxLifetime(user))
+	if now.After(absoluteExpiry) {
+		return nil, errors.Unauthorized(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("Refresh.MaxLifetime"),
+			errors.WithMessage("session has reached its maximum lifetime, please log in again"),
+			errors.WithErrorCode("auth/refresh-token-expired"),
+		)
+	}
+
+	used, err := s.refreshTokenRepo.MarkUsed(stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, s.revokeReusedFamily(ctx, stored)
+	}
+
+	if err := s.sessionRepo.Touch(session.ID); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, user, session.ID, stored, absoluteExpiry)
+}
+
+// revokeReusedFamily revokes every token descending from the same login and deactivates the session
+func (s *AuthService) revokeReusedFamily(ctx context.Context, token *model.RefreshToken) error {
+	if err := s.refreshTokenRepo.RevokeFamily(token.FamilyID); err != nil {
+		return err
+	}
+	if _, err := s.revokeSession(ctx, token.UserID, token.SessionID); err != nil {
+		return err
+	}
+
+	return errors.Unauthorized(
+		errors.WithScope("AuthService"),
+		errors.WithLocation("Refresh.Reused"),
+		errors.WithMessage("refresh token has already been used, session revoked"),
+		errors.WithErrorCode("auth/refresh-token-reused"),
+	)
+}
+
+// ValidateAccessToken verifies signature, issuer and expiry of an access token and makes
+// sure it has not been revoked, either in Redis or by deactivating its session
+func (s *AuthService) ValidateAccessToken(ctx context.Context, accessToken string) (*dto.AppClaims, error) {
+	claims, err := s.parseAccessToken(accessToken, true)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := s.tokenCacheRedis.GetUserIDFromToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if userID != claims.UserID {
+		return nil, errors.Unauthorized(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("ValidateAccessToken.UserMismatch"),
+			errors.WithMessage("invalid access token"),
+			errors.WithErrorCode("auth/invalid-token"),
+		)
+	}
+
+	session, err := s.sessionRepo.FindByID(claims.SessionID)
+	if err != nil || !session.Active || session.UserID != claims.UserID {
+		return nil, errors.Unauthorized(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("ValidateAccessToken.SessionInactive"),
+			errors.WithMessage("session is no longer active"),
+			errors.WithErrorCode("auth/session-inactive"),
+		)
+	}
+
+	return claims, nil
+}
+
+// Introspect reports whether an access token is still usable, for other services (RFC 7662).
+// Results are cached briefly and dropped as soon as the token's session is revoked.
+func (s *AuthService) Introspect(ctx context.Context, accessToken string) (*dto.IntrospectionResponse, error) {
+	if cached, found, err := s.tokenCacheRedis.GetIntrospection(ctx, accessToken); err != nil {
+		return nil, err
+	} else if found {
+		if cached.Active && time.Now().Unix() >= cached.Exp {
+			return &dto.IntrospectionResponse{Active: false}, nil
+		}
+		return cached, nil
+	}
+
+	resp := &dto.IntrospectionResponse{Active: false}
+	ttl := s.config.IntrospectionCacheTTL
+
+	claims, err := s.ValidateAccessToken(ctx, accessToken)
+	if err == nil {
+		resp = &dto.IntrospectionResponse{
+			Active:      true,
+			Sub:         claims.UserID,
+			SessionID:   claims.SessionID,
+			TokenType:   "access_token",
+			Exp:         claims.ExpiresAt.Unix(),
+			Iss:         claims.Issuer,
+			UserType:    claims.UserType,
+			Scope:       strings.Join(claims.Scopes, " "),
+			Permissions: claims.Permissions,
+		}
+		if claims.IssuedAt != nil {
+			resp.Iat = claims.IssuedAt.Unix()
+		}
+		if claims.Role != "" {
+			resp.Roles = []string{claims.Role}
+		}
+		if remaining := time.Until(claims.ExpiresAt.Time); remaining < ttl {
+			ttl = remaining
+		}
+	} else if ext, ok := err.(*errors.Extension); !ok || ext.StatusCode >= 500 {
+		// Never cache or report an outage as a revoked token
+		return nil, err
+	}
+
+	if ttl > 0 {
+		if err := s.tokenCacheRedis.SetIntrospection(ctx, accessToken, resp, ttl); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// Logout ends the session the access token belongs to. An expired but correctly
+// signed token is still accepted so clients can always clean up.
+func (s *AuthService) Logout(ctx context.Context, accessToken string) (*dto.LogoutResponse, error) {
+	claims, err := s.parseAccessToken(accessToken, false)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.revokeSession(ctx, claims.UserID, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LogoutResponse{
+		Message:         "logged out",
+		SessionsRevoked: revoked,
+	}, nil
+}
+
+// LogoutAll ends every session of the user the access token belongs to
+func (s *AuthService) LogoutAll(ctx context.Context, accessToken string) (*dto.LogoutResponse, error) {
+	claims, err := s.parseAccessToken(accessToken, false)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.RevokeAllSessions(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LogoutResponse{
+		Message:         "logged out from all sessions",
+		SessionsRevoked: revoked,
+	}, nil
+}
+
+// enforceSessionPolicy makes room for a new session of the given device class by
+// revoking existing sessions according to the user's concurrent-session policy
+func (s *AuthService) enforceSessionPolicy(ctx context.Context, user *model.User, deviceClass constant.DeviceClass) error {
+	policy := s.config.SessionPolicyFor(user.Role, userTypeOf(user))
+
+	switch policy.Policy {
+	case constant.SessionPolicyUnlimited:
+		return nil
+	case constant.SessionPolicyPerDevice, constant.SessionPolicyMaxSessions:
+	default:
+		_, err := s.RevokeAllSessions(ctx, user.ID)
+		return err
+	}
+
+	sessions, err := s.sessionRepo.FindActiveByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+
+	var evicted []model.Session
+	if policy.Policy == constant.SessionPolicyPerDevice {
+		for _, session := range sessions {
+			if sessionDeviceClass(&session) == deviceClass {
+				evicted = append(evicted, session)
+			}
+		}
+	} else if excess := len(sessions) - (policy.MaxSessions - 1); excess > 0 {
+		sort.Slice(sessions, func(i, j int) bool {
+			return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+		})
+		evicted = sessions[:excess]
+	}
+
+	for _, session := range evicted {
+		if _, err := s.revokeSession(ctx, user.ID, session.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sessionDeviceClass reads the device class recorded with the session, falling back
+// to parsing the raw user agent for sessions created before it was recorded
+func sessionDeviceClass(session *model.Session) constant.DeviceClass {
+	var userAgent dto.UserAgent
+	if session.UserAgent.Valid {
+		_ = json.Unmarshal([]byte(session.UserAgent.String), &userAgent)
+	}
+	if userAgent.DeviceClass != "" {
+		return userAgent.DeviceClass
+	}
+	return utils.ParseUserAgent(userAgent.Raw).Class
+}
+
+// RevokeAllSessions deactivates every session of a user together with its refresh and access tokens
+func (s *AuthService) RevokeAllSessions(ctx context.Context, userID string) (int64, error) {
+	revoked, err := s.sessionRepo.DeactivateSessionsByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.refreshTokenRepo.RevokeByUserID(userID); err != nil {
+		return 0, err
+	}
+	if err := s.tokenCacheRedis.DeleteUserTokens(ctx, userID); err != nil {
+		return 0, err
+	}
+
+	return revoked, nil
+}
+
+// RevokeOtherSessions deactivates every session of a user except keepSessionID
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID, keepSessionID string) (int64, error) {
+	sessions, err := s.sessionRepo.FindActiveByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	var revoked int64
+	for _, session := range sessions {
+		if session.ID == keepSessionID {
+			continue
+		}
+		n, err := s.revokeSession(ctx, userID, session.ID)
+		if err != nil {
+			return revoked, err
+		}
+		revoked += n
+	}
+
+	return revoked, nil
+}
+
+// revokeSession deactivates one session together with its refresh and access tokens
+func (s *AuthService) revokeSession(ctx context.Context, userID, sessionID string) (int64, error) {
+	revoked, err := s.sessionRepo.DeactivateSession(sessionID)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.refreshTokenRepo.RevokeBySessionID(sessionID); err != nil {
+		return 0, err
+	}
+	if err := s.tokenCacheRedis.DeleteSessionTokens(ctx, userID, sessionID); err != nil {
+		return 0, err
+	}
+
+	return revoked, nil
+}
+
+// parseAccessToken verifies the signature of an access token and returns its claims.
+// When validateClaims is false, registered claims such as exp are not checked.
+func (s *AuthService) parseAccessToken(accessToken string, validateClaims bool) (*dto.AppClaims, error) {
+	claims, typ, err := s.parseToken(accessToken, validateClaims)
+	if err != nil || claims.UserID == "" || claims.SessionID == "" || !isAccessToken(claims, typ) {
+		detail := "missing user or session claim, or not an access token"
+		if err != nil {
+			detail = err.Error()
+		}
+		return nil, errors.Unauthorized(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("parseAccessToken"),
+			errors.WithMessage("invalid access token"),
+			errors.WithErrorCode("auth/invalid-token"),
+			errors.WithDetail(detail),
+		)
+	}
+
+	return claims, nil
+}
+
+// isAccessToken tells access tokens apart from the other tokens signed with the same keys.
+// Untyped tokens are accepted as long as they carry no scope or audience, those were issued
+// before tokens were typed and are all access tokens.
+func isAccessToken(claims *dto.AppClaims, typ constant.TokenType) bool {
+	if typ == constant.TokenTypeAccess {
+		return true
+	}
+	return typ == constant.TokenTypeJWT && len(claims.Scopes) == 0 && len(claims.Audience) == 0
+}
+
+// parseToken verifies the signature of any token this service issued and returns its claims
+// together with its typ header
+func (s *AuthService) parseToken(token string, validateClaims bool, extra ...jwt.ParserOption) (*dto.AppClaims, constant.TokenType, error) {
+	options := append([]jwt.ParserOption{jwt.WithValidMethods(s.keyManager.ValidMethods())}, extra...)
+	if !validateClaims {
+		options = append(options, jwt.WithoutClaimsValidation())
+	} else {
+		options = append(options, jwt.WithExpirationRequired())
+		if s.config.JwtIssuer != "" {
+			options = append(options, jwt.WithIssuer(s.config.JwtIssuer))
+		}
+	}
+
+	var claims dto.AppClaims
+	parsed, err := jwt.ParseWithClaims(token, &claims, s.keyManager.Keyfunc, options...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	typ, _ := parsed.Header["typ"].(string)
+	return &claims, constant.TokenType(typ), nil
+}
+
+// issueStepUpToken signs a short-lived token proving the session owner just passed an
+// extra verification. It is never stored in Redis, so it cannot be used as an access token.
+// Services guarding money-moving operations verify it against the JWKS: typ step-up+jwt,
+// the scope as audience and the same sid as the caller's access token.
+func (s *AuthService) issueStepUpToken(claims *dto.AppClaims, scope constant.TokenScope) (*dto.StepUpResponse, error) {
+	signedToken, scoped, err := s.signScopedToken(constant.TokenTypeStepUp, dto.AppClaims{
+		UserID:       claims.UserID,
+		UserType:     claims.UserType,
+		UserToken:    claims.UserToken,
+		InvestorType: claims.InvestorType,
+		FirstName:    claims.FirstName,
+		LastName:     claims.LastName,
+		SessionID:    claims.SessionID,
+		Version:      dto.ClaimsVersion,
+	}, scope, s.config.StepUpTokenExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.StepUpResponse{
+		StepUpToken: signedToken,
+		Scope:       string(scope),
+		ExpiresAt:   scoped.ExpiresAt.Time,
+	}, nil
+}
+
+// signScopedToken signs claims restricted to a single scope with a fresh jti and the given lifetime.
+// The scope is also the audience, so verifiers that only know the JWKS can tell the token is not
+// an access token.
+func (s *AuthService) signScopedToken(typ constant.TokenType, claims dto.AppClaims, scope constant.TokenScope, ttl time.Duration) (string, *dto.AppClaims, error) {
+	now := time.Now()
+	claims.Scopes = []string{string(scope)}
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.New().String(),
+		Audience:  jwt.ClaimStrings{string(scope)},
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		Issuer:    s.config.JwtIssuer,
+	}
+
+	signedToken, err := s.keyManager.Sign(claims, string(typ))
+	if err != nil {
+		return "", nil, errors.InternalServerError(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("signScopedToken.Sign"),
+			errors.WithMessage("failed to sign token"),
+			errors.WithErrorCode("auth/token-signing-failed"),
+			errors.WithDetail(err.Error()),
+		)
+	}
+
+	return signedToken, &claims, nil
+}
+
+// parseScopedToken verifies a token produced by signScopedToken for the expected type and scope
+func (s *AuthService) parseScopedToken(token string, typ constant.TokenType, scope constant.TokenScope) (*dto.AppClaims, error) {
+	claims, parsedTyp, err := s.parseToken(token, true, jwt.WithAudience(string(scope)))
+	if err != nil || parsedTyp != typ || claims.UserID == "" || !slices.Contains(claims.Scopes, string(scope)) {
+		return nil, errors.Unauthorized(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("parseScopedToken"),
+			errors.WithMessage("invalid or expired token"),
+			errors.WithErrorCode("auth/invalid-token"),
+		)
+	}
+
+	return claims, nil
+}
+
+// issueTokens signs an access token and persists a new hashed refresh token for the session.
+// A nil parent starts a new token family; otherwise the new token joins the parent's family.
+// The refresh token never outlives absoluteExpiry.
+func (s *AuthService) issueTokens(ctx context.Context, user *model.User, sessionID string, parent *model.RefreshToken, absoluteExpiry time.Time) (*dto.LoginResponse, error) {
+	now := time.Now()
+	accessTokenExpiry := s.accessTokenExpiry(user)
+	signedToken, err := s.keyManager.Sign(dto.AppClaims{
+		UserID:       user.ID,
+		UserType:     string(userTypeOf(user)),
+		UserToken:    user.InvestorType.String,
+		InvestorType: user.InvestorType.String,
+		FirstName:    user.FirstName,
+		LastName:     user.LastName,
+		SessionID:    sessionID,
+		Role:         user.Role,
+		Permissions:  s.permissions.PermissionsFor(user.Role),
+		Version:      dto.ClaimsVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenExpiry)),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    s.config.JwtIssuer,
 		},
-	})
+	}, string(constant.TokenTypeAccess))
+	if err != nil {
+		return nil, errors.InternalServerError(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("issueTokens.Sign"),
+			errors.WithMessage("failed to sign access token"),
+			errors.WithErrorCode("auth/token-signing-failed"),
+			errors.WithDetail(err.Error()),
+		)
+	}
 
-	signedToken, _ := token.SignedString([]byte(s.config.JwtSecret))
-	refreshToken := uuid.New().String()
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, errors.InternalServerError(
+			errors.WithScope("AuthService"),
+			errors.WithLocation("issueTokens.GenerateRandomToken"),
+			errors.WithMessage("failed to generate refresh token"),
+			errors.WithErrorCode("auth/token-generation-failed"),
+			errors.WithDetail(err.Error()),
+		)
+	}
+
+	expiresAt := now.Add(s.config.RefreshTokenExpiry)
+	if expiresAt.After(absoluteExpiry) {
+		expiresAt = absoluteExpiry
+	}
 
-	s.tokenCacheRedis.SetAccessToken(ctx, user.ID, signedToken, 1*time.Hour)
-	s.rateLimiterRedis.Reset(ctx, uniqueLabel)
+	next := &model.RefreshToken{
+		ID:        uuid.New().String(),
+		SessionID: sessionID,
+		UserID:    user.ID,
+		FamilyID:  uuid.New().String(),
+		TokenHash: utils.CryptoHash(refreshToken),
+		ExpiresAt: expiresAt,
+	}
+	if parent != nil {
+		next.FamilyID = parent.FamilyID
+		next.ParentID = sql.NullString{String: parent.ID, Valid: true}
+	}
+
+	// ValidateAccessToken requires the cache entry, a token that was not cached is unusable
+	if err := s.tokenCacheRedis.SetAccessToken(ctx, user.ID, sessionID, signedToken, accessTokenExpiry); err != nil {
+		return nil, err
+	}
+
+	if err := s.refreshTokenRepo.Create(next); err != nil {
+		return nil, err
+	}
 
 	return &dto.LoginResponse{
 		AccessToken:  signedToken,
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/saifoelloh/ranger/internal/config"
+)
+
+func TestCheckAdminIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, office, _ := net.ParseCIDR("203.0.113.0/24")
+	s := &AuthService{config: config.Config{AdminIPAllowlist: []*net.IPNet{office}}}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		wantErr    bool
+	}{
+		{name: "allowlisted address", remoteAddr: "203.0.113.10:40000"},
+		{name: "other address", remoteAddr: "198.51.100.7:40000", wantErr: true},
+		{name: "spoofed allowlisted X-Forwarded-For", remoteAddr: "198.51.100.7:40000", forwarded: "203.0.113.10", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The router trusts no proxy, as configured by default
+			router := gin.New()
+			if err := router.SetTrustedProxies(nil); err != nil {
+				t.Fatal(err)
+			}
+			var err error
+			router.POST("/admin/login", func(c *gin.Context) { err = s.checkAdminIP(c.ClientIP()) })
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/login", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkAdminIP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("empty allowlist allows every address", func(t *testing.T) {
+		if err := (&AuthService{}).checkAdminIP("198.51.100.7"); err != nil {
+			t.Errorf("checkAdminIP() error = %v, want nil", err)
+		}
+	})
+}
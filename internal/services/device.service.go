@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/saifoelloh/ranger/internal/config"
+	"github.com/saifoelloh/ranger/internal/constant"
+	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/internal/model"
+	"github.com/saifoelloh/ranger/internal/redis"
+	repository "github.com/saifoelloh/ranger/internal/repositories"
+	"github.com/saifoelloh/ranger/internal/utils"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+// DeviceService implements challenge-response authentication with the key pair a device
+// registered as public_key at login. Challenges look like "<purpose>:<nonce>:<unix expiry>",
+// the device signs that exact string and sends it back with the signature. A session may
+// have several outstanding challenges, each redeemable once.
+type DeviceService struct {
+	config         config.Config
+	userRepo       *repository.UserRepository
+	sessionRepo    *repository.SessionRepository
+	challengeRedis *redis.DeviceChallengeRepository
+	authService    *AuthService
+}
+
+func NewDeviceService(
+	config config.Config,
+	userRepo *repository.UserRepository,
+	sessionRepo *repository.SessionRepository,
+	challengeRedis *redis.DeviceChallengeRepository,
+	authService *AuthService,
+) *DeviceService {
+	return &DeviceService{
+		config:         config,
+		userRepo:       userRepo,
+		sessionRepo:    sessionRepo,
+		challengeRedis: challengeRedis,
+		authService:    authService,
+	}
+}
+
+// IssueLoginChallenge starts a password-less re-login for a device-bound session
+func (s *DeviceService) IssueLoginChallenge(ctx context.Context, sessionID string) (*dto.DeviceChallengeResponse, error) {
+	session, err := s.findBoundSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueChallenge(ctx, session, constant.ChallengePurposeLogin)
+}
+
+// Login verifies a signed login challenge and replaces the old session with a fresh one on the same
+// device. Admin sessions keep the start of the old one, the device key is not a second factor.
+func (s *DeviceService) Login(ctx context.Context, req dto.DeviceLoginInput) (*dto.LoginResponse, error) {
+	session, err := s.findBoundSession(req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyChallenge(ctx, session, constant.ChallengePurposeLogin, req.Challenge, req.Signature); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(session.UserID)
+	if err != nil {
+		return nil, errors.Unauthorized(
+			errors.WithScope("DeviceService"),
+			errors.WithLocation("Login.FindUser"),
+			errors.WithMessage("user no longer exists"),
+			errors.WithErrorCode("auth/user-not-found"),
+		)
+	}
+
+	if _, err := s.authService.revokeSession(ctx, session.UserID, session.ID); err != nil {
+		return nil, err
+	}
+
+	return s.authService.startSession(ctx, user, dto.LoginInput{
+		Device:        session.Device,
+		MacAddress:    session.MacAddress,
+		PublicKey:     session.PublicKey,
+		UserAgent:     req.UserAgent,
+		IP:            req.IP,
+		Location:      req.Location,
+		ClientVersion: req.ClientVersion,
+		DeviceClass:   req.DeviceClass,
+	}, session.CreatedAt)
+}
+
+// IssueStepUpChallenge asks the device behind the current session to prove possession of its key
+func (s *DeviceService) IssueStepUpChallenge(ctx context.Context, claims *dto.AppClaims) (*dto.DeviceChallengeResponse, error) {
+	session, err := s.findBoundSession(claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueChallenge(ctx, session, constant.ChallengePurposeStepUp)
+}
+
+// VerifyStepUp verifies a signed step-up challenge and returns a short-lived step-up token
+func (s *DeviceService) VerifyStepUp(ctx context.Context, claims *dto.AppClaims, challenge, signature string) (*dto.StepUpResponse, error) {
+	session, err := s.findBoundSession(claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyChallenge(ctx, session, constant.ChallengePurposeStepUp, challenge, signature); err != nil {
+		return nil, err
+	}
+
+	return s.authService.issueStepUpToken(claims, constant.ScopeStepUpDevice)
+}
+
+func (s *DeviceService) findBoundSession(sessionID string) (*model.Session, error) {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || !session.Active || session.PublicKey == "" {
+		return nil, errors.NotFound(
+			errors.WithScope("DeviceService"),
+			errors.WithLocation("findBoundSession"),
+			errors.WithMessage("no active device-bound session found"),
+			errors.WithErrorCode("device/session-not-found"),
+		)
+	}
+
+	return session, nil
+}
+
+func (s *DeviceService) issueChallenge(ctx context.Context, session *model.Session, purpose constant.ChallengePurpose) (*dto.DeviceChallengeResponse, error) {
+	nonce, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, errors.InternalServerError(
+			errors.WithScope("DeviceService"),
+			errors.WithLocation("issueChallenge.GenerateRandomToken"),
+			errors.WithMessage("failed to generate challenge"),
+			errors.WithErrorCode("device/challenge-generation-failed"),
+			errors.WithDetail(err.Error()),
+		)
+	}
+
+	expiresAt := time.Now().Add(s.config.DeviceChallengeExpiry)
+	challenge := fmt.Sprintf("%s:%s:%d", purpose, nonce, expiresAt.Unix())
+
+	if err := s.challengeRedis.Save(ctx, session.ID, utils.CryptoHash(challenge), s.config.DeviceChallengeExpiry); err != nil {
+		return nil, err
+	}
+
+	return &dto.DeviceChallengeResponse{
+		SessionID: session.ID,
+		Challenge: challenge,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (s *DeviceService) verifyChallenge(ctx context.Context, session *model.Session, purpose constant.ChallengePurpose, challenge, signature string) error {
+	invalid := errors.Unauthorized(
+		errors.WithScope("DeviceService"),
+		errors.WithLocation("verifyChallenge"),
+		errors.WithMessage("invalid or expired device challenge"),
+		errors.WithErrorCode("device/invalid-challenge"),
+	)
+
+	parts := strings.Split(challenge, ":")
+	if len(parts) != 3 || parts[0] != string(purpose) {
+		return invalid
+	}
+
+	// Consume before checking anything else so a challenge can never be tried twice
+	consumed, err := s.challengeRedis.Consume(ctx, session.ID, utils.CryptoHash(challenge))
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return invalid
+	}
+
+	expiry, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > expiry {
+		return invalid
+	}
+
+	publicKey, err := utils.ParsePublicKey(session.PublicKey)
+	if err != nil {
+		return invalid
+	}
+
+	if !utils.VerifySignature(publicKey, []byte(challenge), signature) {
+		return invalid
+	}
+
+	return nil
+}
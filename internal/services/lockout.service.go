@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/internal/model"
+	"github.com/saifoelloh/ranger/internal/redis"
+	repository "github.com/saifoelloh/ranger/internal/repositories"
+	"github.com/saifoelloh/ranger/internal/utils"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type LockoutService struct {
+	userRepo    *repository.UserRepository
+	sessionRepo *repository.SessionRepository
+	auditRepo   *repository.AuditRepository
+	rateLimiter *redis.RateLimiterRepository
+}
+
+func NewLockoutService(
+	userRepo *repository.UserRepository,
+	sessionRepo *repository.SessionRepository,
+	auditRepo *repository.AuditRepository,
+	rateLimiter *redis.RateLimiterRepository,
+) *LockoutService {
+	return &LockoutService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		auditRepo:   auditRepo,
+		rateLimiter: rateLimiter,
+	}
+}
+
+// Get reports the attempt counters of a user or an IP address and which of them are locked
+func (s *LockoutService) Get(ctx context.Context, query dto.LockoutQuery) (*dto.LockoutResponse, error) {
+	query, counters, err := s.counters(query)
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := s.rateLimiter.Inspect(ctx, counters)
+	if err != nil {
+		return nil, err
+	}
+
+	return lockoutResponse(query, states), nil
+}
+
+// Clear unlocks a user or an IP address. The action and the state it cleared are recorded in
+// the audit trail before anything is cleared.
+func (s *LockoutService) Clear(ctx context.Context, claims *dto.AppClaims, query dto.LockoutQuery, ip string) (*dto.LockoutResponse, error) {
+	query, counters, err := s.counters(query)
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := s.rateLimiter.Inspect(ctx, counters)
+	if err != nil {
+		return nil, err
+	}
+	resp := lockoutResponse(query, states)
+
+	targetType, targetID := "user", query.UserID
+	if query.IP != "" {
+		targetType, targetID = "ip", query.IP
+	}
+	detail, _ := json.Marshal(resp)
+	err = s.auditRepo.Create(&model.AuditLog{
+		ActorID:    claims.UserID,
+		ActorRole:  claims.Role,
+		Action:     "lockout.clear",
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     sql.NullString{String: query.Reason, Valid: query.Reason != ""},
+		Detail:     string(detail),
+		IP:         ip,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.rateLimiter.Clear(ctx, counters); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// counters resolves the query into the limiter counters it covers
+func (s *LockoutService) counters(query dto.LockoutQuery) (dto.LockoutQuery, []redis.LimiterCounter, error) {
+	if (query.UserID == "") == (query.IP == "") {
+		return query, nil, errors.BadRequest(
+			errors.WithScope("LockoutService"),
+			errors.WithLocation("counters.Query"),
+			errors.WithMessage("exactly one of user_id or ip is required"),
+			errors.WithErrorCode("lockout/invalid-query"),
+		)
+	}
+
+	if query.IP != "" {
+		addr := net.ParseIP(query.IP)
+		if addr == nil {
+			return query, nil, errors.BadRequest(
+				errors.WithScope("LockoutService"),
+				errors.WithLocation("counters.ParseIP"),
+				errors.WithMessage("ip is not a valid IP address"),
+				errors.WithErrorCode("lockout/invalid-ip"),
+			)
+		}
+		query.IP = addr.String()
+		return query, s.rateLimiter.IPCounters(query.IP, utils.IPSubnet(query.IP)), nil
+	}
+
+	user, err := s.userRepo.FindLoginMethods(query.UserID)
+	if err != nil {
+		return query, nil, err
+	}
+
+	accounts := map[string]string{}
+	if user.EmailHash.String != "" {
+		accounts["email"] = user.EmailHash.String
+	}
+	if user.PhoneNumberHash.String != "" {
+		accounts["phone"] = user.PhoneNumberHash.String
+	}
+	for _, platform := range ssoPlatforms {
+		if ssoID := ssoIDOf(user, platform); ssoID != "" {
+			accounts[string(platform)] = ssoLoginAccount(platform, ssoID)
+		}
+	}
+
+	addresses, err := s.sessionRepo.FindMacAddressesByUserID(user.ID)
+	if err != nil {
+		return query, nil, err
+	}
+	// Keyed like loginAttemptOf keys the device of a login attempt
+	devices := []string{}
+	for _, address := range addresses {
+		device := strings.ToLower(strings.TrimSpace(address))
+		if device != "" && !slices.Contains(devices, device) {
+			devices = append(devices, device)
+		}
+	}
+
+	return query, s.rateLimiter.UserCounters(user.ID, accounts, devices, user.EmailHash.String), nil
+}
+
+func lockoutResponse(query dto.LockoutQuery, states []redis.CounterState) *dto.LockoutResponse {
+	resp := &dto.LockoutResponse{
+		UserID:   query.UserID,
+		IP:       query.IP,
+		Tripped:  []string{},
+		Counters: make([]dto.LockoutCounterResponse, 0, len(states)),
+	}
+
+	for _, state := range states {
+		if state.Locked {
+			resp.Locked = true
+			if !slices.Contains(resp.Tripped, state.Dimension) {
+				resp.Tripped = append(resp.Tripped, state.Dimension)
+			}
+		}
+		resp.Counters = append(resp.Counters, dto.LockoutCounterResponse{
+			Dimension:   state.Dimension,
+			Subject:     state.Subject,
+			Attempts:    state.Attempts,
+			MaxAttempts: state.MaxAttempts(),
+			Locked:      state.Locked,
+			AtThreshold: state.AtThreshold,
+			RetryAfter:  int64(math.Ceil(state.RetryAfter.Seconds())),
+			NextLockout: int64(math.Ceil(state.NextLockout.Seconds())),
+			ResetIn:     int64(math.Ceil(state.ResetIn.Seconds())),
+			Offenses:    state.Offenses,
+		})
+	}
+
+	return resp
+}
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/saifoelloh/ranger/internal/config"
+	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/internal/redis"
+	repository "github.com/saifoelloh/ranger/internal/repositories"
+	"github.com/saifoelloh/ranger/internal/utils"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+// totpSkew tolerates one 30 second step of clock drift on the device
+const totpSkew = 1
+
+type MFAService struct {
+	config           config.Config
+	userRepo         *repository.UserRepository
+	mfaRepo          *repository.MFARepository
+	rateLimiterRedis *redis.RateLimiterRepository
+}
+
+func NewMFAService(
+	config config.Config,
+	userRepo *repository.UserRepository,
+	mfaRepo *repository.MFARepository,
+	rateLimiterRedis *redis.RateLimiterRepository,
+) *MFAService {
+	return &MFAService{
+		config:           config,
+		userRepo:         userRepo,
+		mfaRepo:          mfaRepo,
+		rateLimiterRedis: rateLimiterRedis,
+	}
+}
+
+// IsEnabled reports whether the user has an activated TOTP second factor. Only a missing
+// enrollment counts as disabled, any other failure is returned so callers never skip the factor.
+func (s *MFAService) IsEnabled(userID string) (bool, error) {
+	totp, err := s.mfaRepo.FindTOTPByUserID(userID)
+	if ext, ok := err.(*errors.Extension); ok && ext.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return totp.Enabled, nil
+}
+
+// Enroll generates a new TOTP secret pending activation. Re-enrolling over an active factor
+// requires a current code, the active factor keeps working until Activate confirms the new one.
+func (s *MFAService) Enroll(ctx context.Context, claims *dto.AppClaims, code string) (*dto.TOTPEnrollResponse, error) {
+	enabled, err := s.IsEnabled(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		if err := s.Verify(ctx, claims.UserID, code); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, errors.InternalServerError(
+			errors.WithScope("MFAService"),
+			errors.WithLocation("Enroll.GenerateTOTPSecret"),
+			errors.WithMessage("failed to generate totp secret"),
+			errors.WithErrorCode("mfa/secret-generation-failed"),
+			errors.WithDetail(err.Error()),
+		)
+	}
+
+	encrypted, err := utils.Encrypt(s.config.MFAEncryptionKey, secret)
+	if err != nil {
+		return nil, errors.InternalServerError(
+			errors.WithScope("MFAService"),
+			errors.WithLocation("Enroll.Encrypt"),
+			errors.WithMessage("failed to protect totp secret"),
+			errors.WithErrorCode("mfa/secret-encryption-failed"),
+			errors.WithDetail(err.Error()),
+		)
+	}
+
+	if err := s.mfaRepo.SavePendingTOTP(user.ID, encrypted); err != nil {
+		return nil, err
+	}
+
+	account := user.ID
+	if user.Email.Valid && user.Email.String != "" {
+		account = user.Email.String
+	}
+
+	return &dto.TOTPEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.config.TOTPIssuer, account, secret),
+	}, nil
+}
+
+// Activate confirms the pending secret with a first code, replaces any active secret with it and
+// hands out fresh recovery codes, invalidating the previous ones
+func (s *MFAService) Activate(ctx context.Context, claims *dto.AppClaims, code string) (*dto.TOTPActivateResponse, error) {
+	totp, err := s.mfaRepo.FindTOTPByUserID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !totp.PendingSecret.Valid {
+		return nil, errors.BadRequest(
+			errors.WithScope("MFAService"),
+			errors.WithLocation("Activate.NoPendingSecret"),
+			errors.WithMessage("no totp enrollment is waiting for activation, enroll first"),
+			errors.WithErrorCode("mfa/no-pending-enrollment"),
+		)
+	}
+
+	step, err := s.matchTOTP(ctx, claims.UserID, totp.PendingSecret.String, code)
+	if err != nil {
+		return nil, err
+	}
+
+	activated, err := s.mfaRepo.ActivatePendingTOTP(claims.UserID, totp.PendingSecret.String, step)
+	if err != nil {
+		return nil, err
+	}
+	if !activated {
+		return nil, errors.Conflict(
+			errors.WithScope("MFAService"),
+			errors.WithLocation("Activate.PendingSecretChanged"),
+			errors.WithMessage("the enrollment was replaced, activate the latest secret"),
+			errors.WithErrorCode("mfa/pending-enrollment-changed"),
+		)
+	}
+	s.rateLimiterRedis.ResetMFA(ctx, claims.UserID)
+
+	codes, err := s.regenerateRecoveryCodes(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TOTPActivateResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable removes the second factor after checking a current TOTP or recovery code
+func (s *MFAService) Disable(ctx context.Context, claims *dto.AppClaims, code string) (*dto.MFADisableResponse, error) {
+	if err := s.Verify(ctx, claims.UserID, code); err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepo.DeleteTOTP(claims.UserID); err != nil {
+		return nil, err
+	}
+
+	return &dto.MFADisableResponse{Message: "two-factor authentication disabled"}, nil
+}
+
+// Verify accepts either a TOTP code or an unused recovery code for an enabled factor
+func (s *MFAService) Verify(ctx context.Context, userID, code string) error {
+	enabled, err := s.IsEnabled(userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return errors.BadRequest(
+			errors.WithScope("MFAService"),
+			errors.WithLocation("Verify.NotEnabled"),
+			errors.WithMessage("two-factor authentication is not enabled"),
+			errors.WithErrorCode("mfa/not-enrolled"),
+		)
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) != 6 {
+		if err := s.rateLimiterRedis.IsMFAAllowed(ctx, userID); err != nil {
+			return err
+		}
+
+		used, err := s.mfaRepo.UseRecoveryCode(userID, utils.CryptoHash(normalizeRecoveryCode(code)))
+		if err != nil {
+			return err
+		}
+		if !used {
+			return invalidMFACode("Verify.RecoveryCode")
+		}
+
+		s.rateLimiterRedis.ResetMFA(ctx, userID)
+		return nil
+	}
+
+	return s.verifyTOTP(ctx, userID, code)
+}
+
+// verifyTOTP checks a code against the active secret and rejects replayed codes
+func (s *MFAService) verifyTOTP(ctx context.Context, userID, code string) error {
+	totp, err := s.mfaRepo.FindTOTPByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	step, err := s.matchTOTP(ctx, userID, totp.Secret, code)
+	if err != nil {
+		return err
+	}
+
+	fresh, err := s.mfaRepo.MarkTOTPStepUsed(userID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return invalidMFACode("verifyTOTP.Replay")
+	}
+
+	s.rateLimiterRedis.ResetMFA(ctx, userID)
+	return nil
+}
+
+// matchTOTP counts an attempt against the MFA rate limit and checks code against an encrypted
+// secret, returning the time step it matched
+func (s *MFAService) matchTOTP(ctx context.Context, userID, encryptedSecret, code string) (int64, error) {
+	if err := s.rateLimiterRedis.IsMFAAllowed(ctx, userID); err != nil {
+		return 0, err
+	}
+
+	secret, err := utils.Decrypt(s.config.MFAEncryptionKey, encryptedSecret)
+	if err != nil {
+		return 0, errors.InternalServerError(
+			errors.WithScope("MFAService"),
+			errors.WithLocation("matchTOTP.Decrypt"),
+			errors.WithMessage("failed to read totp secret"),
+			errors.WithErrorCode("mfa/secret-decryption-failed"),
+			errors.WithDetail(err.Error()),
+		)
+	}
+
+	step, ok := utils.ValidateTOTP(secret, strings.TrimSpace(code), time.Now(), totpSkew)
+	if !ok {
+		return 0, invalidMFACode("matchTOTP.Validate")
+	}
+
+	return step, nil
+}
+
+func (s *MFAService) regenerateRecoveryCodes(userID string) ([]string, error) {
+	codes := make([]string, 0, s.config.RecoveryCodeCount)
+	hashes := make([]string, 0, s.config.RecoveryCodeCount)
+
+	for i := 0; i < s.config.RecoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, errors.InternalServerError(
+				errors.WithScope("MFAService"),
+				errors.WithLocation("regenerateRecoveryCodes.Read"),
+				errors.WithMessage("failed to generate recovery codes"),
+				errors.WithErrorCode("mfa/recovery-code-generation-failed"),
+				errors.WithDetail(err.Error()),
+			)
+		}
+
+		raw := base32.StdEncoding.EncodeToString(b)
+		code := strings.ToLower(raw[:4] + "-" + raw[4:])
+		codes = append(codes, code)
+		hashes = append(hashes, utils.CryptoHash(normalizeRecoveryCode(code)))
+	}
+
+	if err := s.mfaRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode makes recovery codes insensitive to case and separators
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func invalidMFACode(location string) error {
+	return errors.Unauthorized(
+		errors.WithScope("MFAService"),
+		errors.WithLocation(location),
+		errors.WithMessage("invalid verification code"),
+		errors.WithErrorCode("mfa/invalid-code"),
+	)
+}
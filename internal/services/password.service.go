@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/saifoelloh/ranger/internal/config"
+	"github.com/saifoelloh/ranger/internal/constant"
+	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/internal/model"
+	"github.com/saifoelloh/ranger/internal/notifier"
+	"github.com/saifoelloh/ranger/internal/redis"
+	repository "github.com/saifoelloh/ranger/internal/repositories"
+	"github.com/saifoelloh/ranger/internal/utils"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+const passwordResetRequestedMessage = "if the email is registered, a reset link has been sent"
+
+type PasswordService struct {
+	config      config.Config
+	userRepo    *repository.UserRepository
+	historyRepo *repository.PasswordHistoryRepository
+	resetRedis  *redis.PasswordResetRepository
+	rateLimiter *redis.RateLimiterRepository
+	sender      notifier.Sender
+	authService *AuthService
+}
+
+func NewPasswordService(
+	config config.Config,
+	userRepo *repository.UserRepository,
+	historyRepo *repository.PasswordHistoryRepository,
+	resetRedis *redis.PasswordResetRepository,
+	rateLimiter *redis.RateLimiterRepository,
+	sender notifier.Sender,
+	authService *AuthService,
+) *PasswordService {
+	return &PasswordService{
+		config:      config,
+		userRepo:    userRepo,
+		historyRepo: historyRepo,
+		resetRedis:  resetRedis,
+		rateLimiter: rateLimiter,
+		sender:      sender,
+		authService: authService,
+	}
+}
+
+// RequestReset emails a single-use reset link. The response is the same whether or not
+// the email is registered, so it cannot be used to enumerate users.
+func (s *PasswordService) RequestReset(ctx context.Context, req dto.ForgotPasswordRequest) (*dto.PasswordResponse, error) {
+	email := utils.NormalizeEmail(req.Email)
+	if email == "" {
+		return nil, errors.BadRequest(
+			errors.WithScope("PasswordService"),
+			errors.WithLocation("RequestReset.Email"),
+			errors.WithMessage("email is required"),
+			errors.WithErrorCode("password/email-required"),
+		)
+	}
+
+	resp := &dto.PasswordResponse{Message: passwordResetRequestedMessage}
+
+	user, err := s.userRepo.FindByEmail(utils.CryptoHash(email))
+	if err != nil {
+		return resp, nil
+	}
+
+	acquired, err := s.resetRedis.AcquireCooldown(ctx, user.ID, s.config.PasswordResetCooldown)
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return resp, nil
+	}
+
+	token, claims, err := s.authService.signScopedToken(constant.TokenTypePasswordReset, dto.AppClaims{UserID: user.ID}, constant.ScopePasswordReset, s.config.PasswordResetExpiry)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.resetRedis.SaveToken(ctx, claims.ID, user.ID, s.config.PasswordResetExpiry); err != nil {
+		return nil, err
+	}
+
+	err = s.sender.Send(ctx, notifier.Message{
+		Channel: notifier.ChannelEmail,
+		To:      email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Use the link below to reset your password. It expires in %s and can only be used once.\n\n%s?token=%s",
+			s.config.PasswordResetExpiry, s.config.PasswordResetURL, url.QueryEscape(token),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ConfirmReset redeems a reset token, stores the new password and signs the user out everywhere
+func (s *PasswordService) ConfirmReset(ctx context.Context, req dto.ResetPasswordRequest) (*dto.PasswordResponse, error) {
+	claims, err := s.authService.parseScopedToken(req.Token, constant.TokenTypePasswordReset, constant.ScopePasswordReset)
+	if err != nil {
+		return nil, err
+	}
+
+	invalidToken := errors.Unauthorized(
+		errors.WithScope("PasswordService"),
+		errors.WithLocation("ConfirmReset.Token"),
+		errors.WithMessage("reset token is invalid, expired or already used"),
+		errors.WithErrorCode("auth/invalid-reset-token"),
+	)
+
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil || claims.IssuedAt.Time.Before(user.LastChangePassword.Truncate(time.Second)) {
+		return nil, invalidToken
+	}
+
+	// Check the new password before redeeming, so a rejected password does not burn the link
+	if err := s.checkNewPassword(user, req.NewPassword, "ConfirmReset"); err != nil {
+		return nil, err
+	}
+
+	userID, err := s.resetRedis.ConsumeToken(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if userID != user.ID {
+		return nil, invalidToken
+	}
+
+	if err := s.storePassword(user, req.NewPassword, "ConfirmReset"); err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.authService.RevokeAllSessions(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.PasswordResponse{
+		Message:         "password has been reset",
+		SessionsRevoked: revoked,
+	}, nil
+}
+
+// ChangePassword re-authenticates the user with the current password before replacing it.
+// Other sessions are always signed out, the current one only when it is not kept.
+func (s *PasswordService) ChangePassword(ctx context.Context, claims *dto.AppClaims, req dto.ChangePasswordRequest) (*dto.PasswordResponse, error) {
+	if err := s.rateLimiter.IsPasswordAllowed(ctx, claims.UserID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password.String), []byte(req.CurrentPassword)); err != nil {
+		return nil, errors.Unauthorized(
+			errors.WithScope("PasswordService"),
+			errors.WithLocation("ChangePassword.ComparePassword"),
+			errors.WithMessage("current password is incorrect"),
+			errors.WithErrorCode("auth/invalid-credentials"),
+		)
+	}
+	if err := s.rateLimiter.ResetPassword(ctx, claims.UserID); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkNewPassword(user, req.NewPassword, "ChangePassword"); err != nil {
+		return nil, err
+	}
+	if err := s.storePassword(user, req.NewPassword, "ChangePassword"); err != nil {
+		return nil, err
+	}
+
+	var revoked int64
+	if req.KeepCurrentSession {
+		revoked, err = s.authService.RevokeOtherSessions(ctx, user.ID, claims.SessionID)
+	} else {
+		revoked, err = s.authService.RevokeAllSessions(ctx, user.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.PasswordResponse{
+		Message:         "password has been changed",
+		SessionsRevoked: revoked,
+	}, nil
+}
+
+// checkNewPassword applies the password policy and rejects the current and recently used passwords
+func (s *PasswordService) checkNewPassword(user *model.User, newPassword, location string) error {
+	if err := utils.ValidatePassword(newPassword, s.config.PasswordMinLength); err != nil {
+		return errors.BadRequest(
+			errors.WithScope("PasswordService"),
+			errors.WithLocation(location+".ValidatePassword"),
+			errors.WithMessage(err.Error()),
+			errors.WithErrorCode("password/policy-violation"),
+		)
+	}
+
+	histories, err := s.historyRepo.FindRecentByUserID(user.ID, s.config.PasswordHistorySize)
+	if err != nil {
+		return err
+	}
+	previous := make([]string, 0, len(histories)+1)
+	if user.Password.Valid && user.Password.String != "" {
+		previous = append(previous, user.Password.String)
+	}
+	for _, history := range histories {
+		previous = append(previous, history.Password)
+	}
+	for _, hash := range previous {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(newPassword)) == nil {
+			return errors.BadRequest(
+				errors.WithScope("PasswordService"),
+				errors.WithLocation(location+".PasswordReuse"),
+				errors.WithMessage("new password must differ from recently used passwords"),
+				errors.WithErrorCode("password/reused"),
+			)
+		}
+	}
+
+	return nil
+}
+
+// storePassword saves the new hash and keeps the replaced one in the history
+func (s *PasswordService) storePassword(user *model.User, newPassword, location string) error {
+	hashed, err := utils.HashBcrypt(newPassword)
+	if err != nil {
+		return errors.InternalServerError(
+			errors.WithScope("PasswordService"),
+			errors.WithLocation(location+".HashBcrypt"),
+			errors.WithMessage("failed to hash password"),
+			errors.WithErrorCode("password/hash-failed"),
+			errors.WithDetail(err.Error()),
+		)
+	}
+	if err := s.userRepo.UpdatePassword(user.ID, hashed); err != nil {
+		return err
+	}
+	if user.Password.Valid && user.Password.String != "" {
+		if err := s.historyRepo.Create(user.ID, user.Password.String); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
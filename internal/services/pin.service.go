@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/saifoelloh/ranger/internal/config"
+	"github.com/saifoelloh/ranger/internal/constant"
+	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/internal/redis"
+	repository "github.com/saifoelloh/ranger/internal/repositories"
+	"github.com/saifoelloh/ranger/internal/utils"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type PinService struct {
+	config      config.Config
+	pinRepo     *repository.PinRepository
+	rateLimiter *redis.RateLimiterRepository
+	authService *AuthService
+}
+
+func NewPinService(
+	config config.Config,
+	pinRepo *repository.PinRepository,
+	rateLimiter *redis.RateLimiterRepository,
+	authService *AuthService,
+) *PinService {
+	return &PinService{
+		config:      config,
+		pinRepo:     pinRepo,
+		rateLimiter: rateLimiter,
+		authService: authService,
+	}
+}
+
+// SetPin stores the first transaction PIN of the user
+func (s *PinService) SetPin(ctx context.Context, claims *dto.AppClaims, pin string) (*dto.PinResponse, error) {
+	hashed, err := s.hashPin(pin, "SetPin")
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.pinRepo.Create(claims.UserID, hashed)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, errors.Conflict(
+			errors.WithScope("PinService"),
+			errors.WithLocation("SetPin.Create"),
+			errors.WithMessage("pin has already been set, change it instead"),
+			errors.WithErrorCode("pin/already-set"),
+		)
+	}
+
+	return &dto.PinResponse{Message: "pin has been set"}, nil
+}
+
+// ChangePin replaces the PIN after checking the current one
+func (s *PinService) ChangePin(ctx context.Context, claims *dto.AppClaims, req dto.ChangePinRequest) (*dto.PinResponse, error) {
+	if err := s.checkPin(ctx, claims.UserID, req.CurrentPin, "ChangePin"); err != nil {
+		return nil, err
+	}
+	if req.NewPin == req.CurrentPin {
+		return nil, errors.BadRequest(
+			errors.WithScope("PinService"),
+			errors.WithLocation("ChangePin.Reuse"),
+			errors.WithMessage("new pin must differ from the current pin"),
+			errors.WithErrorCode("pin/reused"),
+		)
+	}
+
+	hashed, err := s.hashPin(req.NewPin, "ChangePin")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.pinRepo.Update(claims.UserID, hashed); err != nil {
+		return nil, err
+	}
+
+	return &dto.PinResponse{Message: "pin has been changed"}, nil
+}
+
+// VerifyPin checks the PIN and returns a step-up token for money-moving operations
+func (s *PinService) VerifyPin(ctx context.Context, claims *dto.AppClaims, pin string) (*dto.StepUpResponse, error) {
+	if err := s.checkPin(ctx, claims.UserID, pin, "VerifyPin"); err != nil {
+		return nil, err
+	}
+
+	return s.authService.issueStepUpToken(claims, constant.ScopeStepUpPin)
+}
+
+// checkPin compares a PIN against the stored hash, counting failures towards the PIN lockout
+func (s *PinService) checkPin(ctx context.Context, userID, pin, location string) error {
+	if err := s.rateLimiter.IsPinAllowed(ctx, userID); err != nil {
+		return err
+	}
+
+	stored, err := s.pinRepo.FindByUserID(userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(stored.Pin), []byte(pin)); err != nil {
+		return errors.Unauthorized(
+			errors.WithScope("PinService"),
+			errors.WithLocation(location+".ComparePin"),
+			errors.WithMessage("invalid pin"),
+			errors.WithErrorCode("pin/invalid"),
+		)
+	}
+
+	return s.rateLimiter.ResetPin(ctx, userID)
+}
+
+func (s *PinService) hashPin(pin, location string) (string, error) {
+	if err := utils.ValidatePin(pin, s.config.PinLength); err != nil {
+		return "", errors.BadRequest(
+			errors.WithScope("PinService"),
+			errors.WithLocation(location+".ValidatePin"),
+			errors.WithMessage(err.Error()),
+			errors.WithErrorCode("pin/policy-violation"),
+		)
+	}
+
+	hashed, err := utils.HashBcrypt(pin)
+	if err != nil {
+		return "", errors.InternalServerError(
+			errors.WithScope("PinService"),
+			errors.WithLocation(location+".HashBcrypt"),
+			errors.WithMessage("failed to hash pin"),
+			errors.WithErrorCode("pin/hash-failed"),
+			errors.WithDetail(err.Error()),
+		)
+	}
+
+	return hashed, nil
+}
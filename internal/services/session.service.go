@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/internal/model"
+	repository "github.com/saifoelloh/ranger/internal/repositories"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type SessionService struct {
+	sessionRepo *repository.SessionRepository
+	authService *AuthService
+}
+
+func NewSessionService(sessionRepo *repository.SessionRepository, authService *AuthService) *SessionService {
+	return &SessionService{
+		sessionRepo: sessionRepo,
+		authService: authService,
+	}
+}
+
+func (s *SessionService) ListSessions(ctx context.Context, claims *dto.AppClaims) ([]dto.SessionResponse, error) {
+	sessions, err := s.sessionRepo.FindActiveByUserID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]dto.SessionResponse, 0, len(sessions))
+	for i := range sessions {
+		resp = append(resp, toSessionResponse(&sessions[i], claims.SessionID))
+	}
+
+	return resp, nil
+}
+
+func (s *SessionService) GetSession(ctx context.Context, claims *dto.AppClaims, sessionID string) (*dto.SessionResponse, error) {
+	session, err := s.findOwnSession(claims, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := toSessionResponse(session, claims.SessionID)
+	return &resp, nil
+}
+
+func (s *SessionService) RevokeSession(ctx context.Context, claims *dto.AppClaims, sessionID string) (*dto.RevokeSessionResponse, error) {
+	session, err := s.findOwnSession(claims, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !session.Active {
+		return &dto.RevokeSessionResponse{Message: "session already revoked"}, nil
+	}
+
+	if _, err := s.authService.revokeSession(ctx, session.UserID, session.ID); err != nil {
+		return nil, err
+	}
+
+	return &dto.RevokeSessionResponse{Message: "session revoked"}, nil
+}
+
+// findOwnSession hides sessions of other users behind the same not found error
+func (s *SessionService) findOwnSession(claims *dto.AppClaims, sessionID string) (*model.Session, error) {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || session.UserID != claims.UserID {
+		return nil, errors.NotFound(
+			errors.WithScope("SessionService"),
+			errors.WithLocation("findOwnSession"),
+			errors.WithMessage("session not found"),
+			errors.WithErrorCode("session/not-found"),
+		)
+	}
+
+	return session, nil
+}
+
+func toSessionResponse(session *model.Session, currentSessionID string) dto.SessionResponse {
+	var userAgent dto.UserAgent
+	if session.UserAgent.Valid {
+		_ = json.Unmarshal([]byte(session.UserAgent.String), &userAgent)
+	}
+
+	return dto.SessionResponse{
+		ID:            session.ID,
+		Device:        session.Device,
+		MacAddress:    session.MacAddress,
+		DeviceName:    userAgent.Device,
+		Os:            userAgent.Os,
+		IP:            session.IP.String,
+		Location:      session.Location.String,
+		ClientVersion: session.ClientVersion,
+		CreatedAt:     session.CreatedAt,
+		LastSeenAt:    session.UpdatedAt,
+		Current:       session.ID == currentSessionID,
+	}
+}
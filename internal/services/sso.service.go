@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/saifoelloh/ranger/internal/config"
+	"github.com/saifoelloh/ranger/internal/constant"
+	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/internal/model"
+	"github.com/saifoelloh/ranger/internal/redis"
+	repository "github.com/saifoelloh/ranger/internal/repositories"
+	"github.com/saifoelloh/ranger/internal/sso"
+	"github.com/saifoelloh/ranger/internal/utils"
+	"github.com/saifoelloh/ranger/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ssoPlatforms = []constant.SSOPlatform{
+	constant.SSOPlatformGoogle,
+	constant.SSOPlatformApple,
+	constant.SSOPlatformFacebook,
+}
+
+type SSOService struct {
+	config       config.Config
+	userRepo     *repository.UserRepository
+	nonceRedis   *redis.SSONonceRepository
+	rateLimiter  *redis.RateLimiterRepository
+	ssoVerifiers sso.Verifiers
+	authService  *AuthService
+}
+
+func NewSSOService(
+	config config.Config,
+	userRepo *repository.UserRepository,
+	nonceRedis *redis.SSONonceRepository,
+	rateLimiter *redis.RateLimiterRepository,
+	ssoVerifiers sso.Verifiers,
+	authService *AuthService,
+) *SSOService {
+	return &SSOService{
+		config:       config,
+		userRepo:     userRepo,
+		nonceRedis:   nonceRedis,
+		rateLimiter:  rateLimiter,
+		ssoVerifiers: ssoVerifiers,
+		authService:  authService,
+	}
+}
+
+// IssueNonce returns a single-use nonce for the client to pass to the provider sign in. The ID
+// token must carry it, or its SHA-256 hex digest, and it is redeemed when the token is verified.
+func (s *SSOService) IssueNonce(ctx context.Context) (*dto.SSONonceResponse, error) {
+	nonce, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, errors.InternalServerError(
+			errors.WithScope("SSOService"),
+			errors.WithLocation("IssueNonce.GenerateRandomToken"),
+			errors.WithMessage("failed to generate nonce"),
+			errors.WithErrorCode("auth/nonce-generation-failed"),
+			errors.WithDetail(err.Error()),
+		)
+	}
+
+	if err := s.nonceRedis.Save(ctx, sso.NonceDigest(nonce), s.config.SSONonceExpiry); err != nil {
+		return nil, err
+	}
+
+	return &dto.SSONonceResponse{
+		Nonce:     nonce,
+		ExpiresAt: time.Now().Add(s.config.SSONonceExpiry),
+	}, nil
+}
+
+// LinkProvider attaches a verified sso identity to the current account. A stolen access token
+// alone is not enough: the caller proves again it is the owner, see reauthenticate.
+func (s *SSOService) LinkProvider(ctx context.Context, claims *dto.AppClaims, req dto.LinkSSORequest) (*dto.SSOProvidersResponse, error) {
+	if err := s.reauthenticate(ctx, claims, req); err != nil {
+		return nil, err
+	}
+
+	identity, err := s.ssoVerifiers.Verify(ctx, req.SSOPlatform, req.IDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := s.userRepo.FindBySSOID(identity.Subject, req.SSOPlatform)
+	if err != nil {
+		if ext, ok := err.(*errors.Extension); !ok || ext.StatusCode != http.StatusNotFound {
+			return nil, err
+		}
+	} else if owner.ID != claims.UserID {
+		return nil, errors.Conflict(
+			errors.WithScope("SSOService"),
+			errors.WithLocation("LinkProvider.FindBySSOID"),
+			errors.WithMessage("this sso account is linked to another user"),
+			errors.WithErrorCode("auth/sso-already-linked"),
+		)
+	}
+
+	if err := s.userRepo.LinkSSO(claims.UserID, req.SSOPlatform, identity.Subject); err != nil {
+		return nil, err
+	}
+
+	return s.ListProviders(ctx, claims)
+}
+
+// reauthenticate accepts a device step-up token issued to the caller's session, or else checks
+// the current password under the password rate limit. Accounts without a password use step-up.
+func (s *SSOService) reauthenticate(ctx context.Context, claims *dto.AppClaims, req dto.LinkSSORequest) error {
+	if req.StepUpToken != "" {
+		stepUp, err := s.authService.parseScopedToken(req.StepUpToken, constant.TokenTypeStepUp, constant.ScopeStepUpDevice)
+		if err != nil {
+			return err
+		}
+		if stepUp.UserID != claims.UserID || stepUp.SessionID != claims.SessionID {
+			return errors.Unauthorized(
+				errors.WithScope("SSOService"),
+				errors.WithLocation("reauthenticate.StepUpSession"),
+				errors.WithMessage("step-up token was issued to another session"),
+				errors.WithErrorCode("auth/invalid-token"),
+			)
+		}
+		return nil
+	}
+
+	if req.CurrentPassword == "" {
+		return errors.Unauthorized(
+			errors.WithScope("SSOService"),
+			errors.WithLocation("reauthenticate.Missing"),
+			errors.WithMessage("current_password or step_up_token is required"),
+			errors.WithErrorCode("auth/reauthentication-required"),
+		)
+	}
+
+	if err := s.rateLimiter.IsPasswordAllowed(ctx, claims.UserID); err != nil {
+		return err
+	}
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password.String), []byte(req.CurrentPassword)); !user.Password.Valid || err != nil {
+		return errors.Unauthorized(
+			errors.WithScope("SSOService"),
+			errors.WithLocation("reauthenticate.ComparePassword"),
+			errors.WithMessage("current password is incorrect"),
+			errors.WithErrorCode("auth/invalid-credentials"),
+		)
+	}
+
+	return s.rateLimiter.ResetPassword(ctx, claims.UserID)
+}
+
+func (s *SSOService) ListProviders(ctx context.Context, claims *dto.AppClaims) (*dto.SSOProvidersResponse, error) {
+	user, err := s.userRepo.FindLoginMethods(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.SSOProvidersResponse{Providers: make([]dto.SSOProviderResponse, 0, len(ssoPlatforms))}
+	for _, platform := range ssoPlatforms {
+		resp.Providers = append(resp.Providers, dto.SSOProviderResponse{
+			SSOPlatform: platform,
+			Linked:      ssoLinked(user, platform),
+			Primary:     user.SsoSignOption.String == string(platform),
+		})
+	}
+
+	return resp, nil
+}
+
+// UnlinkProvider detaches a platform unless it is the last way left to sign in to the account
+func (s *SSOService) UnlinkProvider(ctx context.Context, claims *dto.AppClaims, platform constant.SSOPlatform) (*dto.SSOProvidersResponse, error) {
+	if !slices.Contains(ssoPlatforms, platform) {
+		return nil, errors.BadRequest(
+			errors.WithScope("SSOService"),
+			errors.WithLocation("UnlinkProvider.Platform"),
+			errors.WithMessage("unsupported sso_platform"),
+			errors.WithErrorCode("auth/unsupported-sso-platform"),
+		)
+	}
+
+	user, err := s.userRepo.FindLoginMethods(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !ssoLinked(user, platform) {
+		return nil, errors.NotFound(
+			errors.WithScope("SSOService"),
+			errors.WithLocation("UnlinkProvider.Linked"),
+			errors.WithMessage("sso provider is not linked"),
+			errors.WithErrorCode("auth/sso-not-linked"),
+		)
+	}
+
+	remaining := 0
+	if user.Password.Valid && user.Password.String != "" {
+		remaining++
+	}
+	if user.PhoneNumberVerified {
+		remaining++
+	}
+	for _, other := range ssoPlatforms {
+		if other != platform && ssoLinked(user, other) {
+			remaining++
+		}
+	}
+	if remaining == 0 {
+		return nil, errors.BadRequest(
+			errors.WithScope("SSOService"),
+			errors.WithLocation("UnlinkProvider.LastLoginMethod"),
+			errors.WithMessage("cannot unlink the last way to sign in, set a password or link another provider first"),
+			errors.WithErrorCode("auth/last-login-method"),
+		)
+	}
+
+	if err := s.userRepo.UnlinkSSO(claims.UserID, platform); err != nil {
+		return nil, err
+	}
+
+	return s.ListProviders(ctx, claims)
+}
+
+func ssoLinked(user *model.User, platform constant.SSOPlatform) bool {
+	return ssoIDOf(user, platform) != ""
+}
+
+// ssoIDOf returns the sso id the user linked for platform, empty when not linked
+func ssoIDOf(user *model.User, platform constant.SSOPlatform) string {
+	switch platform {
+	case constant.SSOPlatformGoogle:
+		return user.GoogleSsoId.String
+	case constant.SSOPlatformApple:
+		return user.AppleSsoId.String
+	case constant.SSOPlatformFacebook:
+		return user.FacebookSsoId.String
+	}
+	return ""
+}
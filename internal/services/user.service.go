@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/saifoelloh/ranger/internal/config"
+	"github.com/saifoelloh/ranger/internal/constant"
+	"github.com/saifoelloh/ranger/internal/dto"
+	"github.com/saifoelloh/ranger/internal/model"
+	"github.com/saifoelloh/ranger/internal/notifier"
+	"github.com/saifoelloh/ranger/internal/redis"
+	repository "github.com/saifoelloh/ranger/internal/repositories"
+	"github.com/saifoelloh/ranger/internal/utils"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+type UserService struct {
+	config      config.Config
+	userRepo    *repository.UserRepository
+	otpRedis    *redis.OTPRepository
+	rateLimiter *redis.RateLimiterRepository
+	sender      notifier.Sender
+}
+
+func NewUserService(
+	config config.Config,
+	userRepo *repository.UserRepository,
+	otpRedis *redis.OTPRepository,
+	rateLimiter *redis.RateLimiterRepository,
+	sender notifier.Sender,
+) *UserService {
+	return &UserService{
+		config:      config,
+		userRepo:    userRepo,
+		otpRedis:    otpRedis,
+		rateLimiter: rateLimiter,
+		sender:      sender,
+	}
+}
+
+// Register creates an investor account and emails a code to verify the address. A phone
+// number verified through the VERIFY_PHONE otp shortly before is stored as verified.
+func (s *UserService) Register(ctx context.Context, req dto.RegisterRequest) (*dto.RegisterResponse, error) {
+	user, err := s.newUser(req)
+	if err != nil {
+		return nil, err
+	}
+
+	emailTaken, phoneTaken, err := s.userRepo.ExistsByEmailOrPhoneHash(user.EmailHash.String, user.PhoneNumberHash.String)
+	if err != nil {
+		return nil, err
+	}
+	if emailTaken || phoneTaken {
+		field := "email"
+		if !emailTaken {
+			field = "phone number"
+		}
+		return nil, errors.Conflict(
+			errors.WithScope("UserService"),
+			errors.WithLocation("Register.Exists"),
+			errors.WithMessage(fmt.Sprintf("%s is already registered", field)),
+			errors.WithErrorCode("user/already-exists"),
+		)
+	}
+
+	if user.PhoneNumberHash.Valid {
+		user.PhoneNumberVerified, err = s.otpRedis.IsVerified(ctx, user.PhoneNumberHash.String)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	code, err := s.newEmailCode(user, "Register")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+
+	// The mark is only used up once it is stored on the account, a failed registration keeps it
+	if user.PhoneNumberVerified {
+		if _, err := s.otpRedis.ConsumeVerified(ctx, user.PhoneNumberHash.String); err != nil {
+			log.Printf("[ERROR] UserService/Register.ConsumeVerified - %s", err.Error())
+		}
+	}
+
+	// The account exists from here on, a failed send is recovered through ResendVerificationCode
+	message := "registration successful, check your email for the verification code"
+	if err := s.sendEmailCode(ctx, user, code); err != nil {
+		log.Printf("[ERROR] UserService/Register.Send - %s", err.Error())
+		message = "registration successful, but the verification email could not be sent. request a new code"
+	}
+
+	return &dto.RegisterResponse{
+		UserID:              user.ID,
+		Message:             message,
+		PhoneNumberVerified: user.PhoneNumberVerified,
+	}, nil
+}
+
+// ResendVerificationCode replaces the verification code of an unverified address and emails it.
+// The response is the same whether or not the address is registered.
+func (s *UserService) ResendVerificationCode(ctx context.Context, req dto.ResendVerificationRequest) (*dto.ResendVerificationResponse, error) {
+	resp := &dto.ResendVerificationResponse{Message: "if the email is registered and not verified yet, a new code has been sent"}
+
+	emailHash := utils.CryptoHash(utils.NormalizeEmail(req.Email))
+	user, err := s.userRepo.FindEmailVerification(emailHash)
+	if err != nil || user.EmailVerified || !user.Email.Valid {
+		return resp, nil
+	}
+
+	acquired, err := s.otpRedis.AcquireEmailCodeCooldown(ctx, emailHash, s.config.EmailVerificationCooldown)
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return resp, nil
+	}
+
+	user.EmailHash = sql.NullString{String: emailHash, Valid: true}
+	code, err := s.newEmailCode(user, "ResendVerificationCode")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SetEmailVerificationCode(user.ID, user.EmailVerificationCode, user.EmailCodeExpiresAt.Time); err != nil {
+		return nil, err
+	}
+	if err := s.sendEmailCode(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// VerifyEmail confirms the address with the code sent at registration
+func (s *UserService) VerifyEmail(ctx context.Context, req dto.VerifyEmailRequest) (*dto.VerifyEmailResponse, error) {
+	emailHash := utils.CryptoHash(utils.NormalizeEmail(req.Email))
+	if err := s.rateLimiter.IsEmailVerificationAllowed(ctx, emailHash); err != nil {
+		return nil, err
+	}
+
+	invalidCode := errors.BadRequest(
+		errors.WithScope("UserService"),
+		errors.WithLocation("VerifyEmail.Code"),
+		errors.WithMessage("invalid verification code"),
+		errors.WithErrorCode("user/invalid-verification-code"),
+	)
+
+	user, err := s.userRepo.FindEmailVerification(emailHash)
+	if err != nil {
+		return nil, invalidCode
+	}
+	if user.EmailVerified {
+		return &dto.VerifyEmailResponse{Verified: true}, nil
+	}
+
+	expected := hashEmailCode(emailHash, strings.TrimSpace(req.Code))
+	if user.EmailVerificationCode == "" || subtle.ConstantTimeCompare([]byte(user.EmailVerificationCode), []byte(expected)) != 1 {
+		return nil, invalidCode
+	}
+	if user.EmailCodeExpiresAt.Valid && time.Now().After(user.EmailCodeExpiresAt.Time) {
+		return nil, errors.BadRequest(
+			errors.WithScope("UserService"),
+			errors.WithLocation("VerifyEmail.Expired"),
+			errors.WithMessage("verification code has expired. request a new code"),
+			errors.WithErrorCode("user/verification-code-expired"),
+		)
+	}
+
+	if err := s.userRepo.MarkEmailVerified(user.ID); err != nil {
+		return nil, err
+	}
+	if err := s.rateLimiter.ResetEmailVerification(ctx, emailHash); err != nil {
+		return nil, err
+	}
+
+	return &dto.VerifyEmailResponse{Verified: true}, nil
+}
+
+// newUser validates the registration input and builds the user to insert
+func (s *UserService) newUser(req dto.RegisterRequest) (*model.User, error) {
+	badRequest := func(location, message, code string) error {
+		return errors.BadRequest(
+			errors.WithScope("UserService"),
+			errors.WithLocation("Register."+location),
+			errors.WithMessage(message),
+			errors.WithErrorCode(code),
+		)
+	}
+
+	firstName := strings.TrimSpace(req.FirstName)
+	if firstName == "" {
+		return nil, badRequest("FirstName", "first name is required", "user/invalid-name")
+	}
+
+	email := utils.NormalizeEmail(req.Email)
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		return nil, badRequest("Email", "invalid email address", "user/invalid-email")
+	}
+
+	if err := utils.ValidatePassword(req.Password, s.config.PasswordMinLength); err != nil {
+		return nil, badRequest("ValidatePassword", err.Error(), "password/policy-violation")
+	}
+
+	var phone, phoneHash sql.NullString
+	if strings.TrimSpace(req.PhoneNumber) != "" {
+		normalized := utils.NormalizePhoneNumber(req.PhoneNumber)
+		if len(strings.TrimPrefix(normalized, "+")) < 8 {
+			return nil, badRequest("PhoneNumber", "invalid phone number", "user/invalid-phone-number")
+		}
+		phone = sql.NullString{String: normalized, Valid: true}
+		phoneHash = sql.NullString{String: utils.CryptoHash(normalized), Valid: true}
+	}
+
+	hashed, err := utils.HashBcrypt(req.Password)
+	if err != nil {
+		return nil, errors.InternalServerError(
+			errors.WithScope("UserService"),
+			errors.WithLocation("Register.HashBcrypt"),
+			errors.WithMessage("failed to hash password"),
+			errors.WithErrorCode("password/hash-failed"),
+			errors.WithDetail(err.Error()),
+		)
+	}
+
+	knowFrom := strings.TrimSpace(req.KnowFrom)
+	return &model.User{
+		ID:              uuid.New().String(),
+		FirstName:       firstName,
+		LastName:        strings.TrimSpace(req.LastName),
+		Email:           sql.NullString{String: email, Valid: true},
+		EmailHash:       sql.NullString{String: utils.CryptoHash(email), Valid: true},
+		Password:        sql.NullString{String: hashed, Valid: true},
+		PhoneNumber:     phone,
+		PhoneNumberHash: phoneHash,
+		Role:            string(constant.RoleInvestor),
+		KnowFrom:        sql.NullString{String: knowFrom, Valid: knowFrom != ""},
+	}, nil
+}
+
+// newEmailCode generates a verification code for the user's address, storing its hash and expiry on user
+func (s *UserService) newEmailCode(user *model.User, location string) (string, error) {
+	code, err := utils.GenerateNumericCode(s.config.OTPLength)
+	if err != nil {
+		return "", errors.InternalServerError(
+			errors.WithScope("UserService"),
+			errors.WithLocation(location+".GenerateNumericCode"),
+			errors.WithMessage("failed to generate verification code"),
+			errors.WithErrorCode("user/code-generation-failed"),
+			errors.WithDetail(err.Error()),
+		)
+	}
+
+	user.EmailVerificationCode = hashEmailCode(user.EmailHash.String, code)
+	user.EmailCodeExpiresAt = sql.NullTime{Time: time.Now().Add(s.config.EmailVerificationExpiry), Valid: true}
+	return code, nil
+}
+
+func (s *UserService) sendEmailCode(ctx context.Context, user *model.User, code string) error {
+	return s.sender.Send(ctx, notifier.Message{
+		Channel: notifier.ChannelEmail,
+		To:      user.Email.String,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Hi %s! Your email verification code is %s. It expires in %s.",
+			user.FirstName, code, s.config.EmailVerificationExpiry,
+		),
+	})
+}
+
+// hashEmailCode salts the verification code with the email hash before it is stored
+func hashEmailCode(emailHash, code string) string {
+	return utils.CryptoHash(emailHash + ":" + code)
+}
@@ -0,0 +1,126 @@
+package sso
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/saifoelloh/ranger/internal/constant"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+// IDTokenVerifier verifies OpenID Connect ID tokens such as the ones issued by Google and Apple
+type IDTokenVerifier struct {
+	platform     constant.SSOPlatform
+	issuers      []string
+	audiences    []string
+	keys         KeySource
+	nonces       NonceStore
+	requireNonce bool
+}
+
+func NewIDTokenVerifier(platform constant.SSOPlatform, issuers, audiences []string, keys KeySource, nonces NonceStore, requireNonce bool) *IDTokenVerifier {
+	return &IDTokenVerifier{
+		platform:     platform,
+		issuers:      issuers,
+		audiences:    audiences,
+		keys:         keys,
+		nonces:       nonces,
+		requireNonce: requireNonce,
+	}
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified any    `json:"email_verified"` // Apple sends "true" as a string
+}
+
+// Verify checks the signature, issuer, audience, expiry and nonce of an ID token. A nonce claim
+// must be one this service issued and is redeemed, so the token cannot be replayed.
+func (v *IDTokenVerifier) Verify(ctx context.Context, token string) (*Identity, error) {
+	if len(v.audiences) == 0 {
+		return nil, errors.InternalServerError(
+			errors.WithScope("SSO"),
+			errors.WithLocation("IDTokenVerifier.Audiences"),
+			errors.WithMessage(fmt.Sprintf("%s sign in is not configured", v.platform)),
+			errors.WithErrorCode("auth/sso-not-configured"),
+		)
+	}
+
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, invalidToken("IDTokenVerifier.Parse", err.Error())
+	}
+
+	if !slices.Contains(v.issuers, claims.Issuer) {
+		return nil, invalidToken("IDTokenVerifier.Issuer", "unexpected issuer "+claims.Issuer)
+	}
+	if !slices.ContainsFunc(claims.Audience, func(aud string) bool { return slices.Contains(v.audiences, aud) }) {
+		return nil, invalidToken("IDTokenVerifier.Audience", "token was issued to another client")
+	}
+	if claims.Subject == "" {
+		return nil, invalidToken("IDTokenVerifier.Subject", "missing subject")
+	}
+	if claims.Nonce == "" {
+		if v.requireNonce {
+			return nil, invalidToken("IDTokenVerifier.Nonce", "missing nonce")
+		}
+	} else {
+		consumed, err := v.nonces.Consume(ctx, nonceDigests(claims.Nonce)...)
+		if err != nil {
+			return nil, err
+		}
+		if !consumed {
+			return nil, invalidToken("IDTokenVerifier.Nonce", "nonce is unknown, expired or already used")
+		}
+	}
+
+	return &Identity{
+		Platform:      v.platform,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+	}, nil
+}
+
+// NonceDigest is the SHA-256 hex digest nonces are stored under
+func NonceDigest(nonce string) string {
+	digest := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(digest[:])
+}
+
+// nonceDigests lists the digests a nonce claim may have been issued under: the claim holds the
+// raw nonce, or its digest when native Apple sign in was given the hashed nonce
+func nonceDigests(claim string) []string {
+	digests := []string{NonceDigest(claim)}
+	if len(claim) == sha256.Size*2 {
+		if _, err := hex.DecodeString(claim); err == nil {
+			digests = append(digests, strings.ToLower(claim))
+		}
+	}
+	return digests
+}
+
+func invalidToken(location, detail string) error {
+	return errors.Unauthorized(
+		errors.WithScope("SSO"),
+		errors.WithLocation(location),
+		errors.WithMessage("invalid sso token"),
+		errors.WithErrorCode("auth/invalid-sso-token"),
+		errors.WithDetail(detail),
+	)
+}
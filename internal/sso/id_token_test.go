@@ -0,0 +1,134 @@
+package sso
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/saifoelloh/ranger/internal/constant"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+func TestNonceDigests(t *testing.T) {
+	raw := "Kq3v8y0m2nW1r9xYzA4bC7dE6fG5hJ0kL1mN2oP3qR4"
+	digest := NonceDigest(raw)
+
+	tests := []struct {
+		name  string
+		claim string
+		want  []string
+	}{
+		{name: "raw nonce", claim: raw, want: []string{digest}},
+		{name: "hashed nonce", claim: digest, want: []string{NonceDigest(digest), digest}},
+		{name: "upper case hashed nonce", claim: strings.ToUpper(digest), want: []string{NonceDigest(strings.ToUpper(digest)), digest}},
+		{name: "digest sized but not hex", claim: strings.Repeat("z", 64), want: []string{NonceDigest(strings.Repeat("z", 64))}},
+		{name: "hex but not digest sized", claim: digest[:32], want: []string{NonceDigest(digest[:32])}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nonceDigests(tt.claim); !slices.Equal(got, tt.want) {
+				t.Errorf("nonceDigests(%q) = %v, want %v", tt.claim, got, tt.want)
+			}
+		})
+	}
+}
+
+// memoryNonces is a NonceStore holding issued nonce digests in memory
+type memoryNonces map[string]bool
+
+func (m memoryNonces) Consume(_ context.Context, digests ...string) (bool, error) {
+	for _, digest := range digests {
+		if m[digest] {
+			delete(m, digest)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type staticKey struct{ key crypto.PublicKey }
+
+func (s staticKey) Key(context.Context, string) (crypto.PublicKey, error) { return s.key, nil }
+
+func TestIDTokenVerifierNonce(t *testing.T) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(nonce string) string {
+		claims := jwt.MapClaims{
+			"iss": "https://accounts.google.com",
+			"aud": "client-id",
+			"sub": "google-user",
+			"iat": time.Now().Unix(),
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+		if nonce != "" {
+			claims["nonce"] = nonce
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	const issued = "server-issued-nonce"
+	tests := []struct {
+		name         string
+		nonce        string // claim embedded in the ID token
+		requireNonce bool
+		wantErr      bool
+	}{
+		{name: "issued nonce", nonce: issued, requireNonce: true},
+		{name: "hashed issued nonce", nonce: NonceDigest(issued), requireNonce: true},
+		{name: "nonce not issued by this service", nonce: "client-chosen-nonce", requireNonce: true, wantErr: true},
+		{name: "missing nonce when required", requireNonce: true, wantErr: true},
+		{name: "missing nonce when optional"},
+		{name: "unknown nonce when optional", nonce: "client-chosen-nonce", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonces := memoryNonces{NonceDigest(issued): true}
+			verifier := NewIDTokenVerifier(
+				constant.SSOPlatformGoogle,
+				[]string{"https://accounts.google.com"},
+				[]string{"client-id"},
+				staticKey{&private.PublicKey},
+				nonces,
+				tt.requireNonce,
+			)
+
+			token := sign(tt.nonce)
+			identity, err := verifier.Verify(context.Background(), token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if ext, ok := err.(*errors.Extension); !ok || ext.StatusCode != 401 {
+					t.Errorf("Verify() error = %v, want 401", err)
+				}
+				return
+			}
+			if identity.Subject != "google-user" {
+				t.Errorf("Verify().Subject = %q, want %q", identity.Subject, "google-user")
+			}
+
+			// The nonce is single-use
+			if tt.nonce != "" {
+				if _, err := verifier.Verify(context.Background(), token); err == nil {
+					t.Errorf("replayed Verify() with nonce %q succeeded", tt.nonce)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package sso
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/saifoelloh/ranger/internal/jwk"
+)
+
+// minRefreshInterval stops unknown key ids in forged tokens from hammering the provider
+const minRefreshInterval = time.Minute
+
+// KeySource resolves the public key a provider signed a token with
+type KeySource interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// RemoteKeySource fetches a provider's published JWKS and caches it. An unknown key id
+// triggers an early refresh so provider key rotation is picked up without waiting for the TTL.
+// The fetch runs without the lock, concurrent callers wait for the one in flight.
+type RemoteKeySource struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	inflight  *keyFetch
+}
+
+// keyFetch is a JWKS fetch in flight, done is closed once err is set
+type keyFetch struct {
+	done chan struct{}
+	err  error
+}
+
+func NewRemoteKeySource(url string, ttl time.Duration) *RemoteKeySource {
+	return &RemoteKeySource{
+		url:    url,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *RemoteKeySource) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	age := time.Since(s.fetchedAt)
+	key, ok := s.keys[kid]
+	stale := s.keys == nil || age >= s.ttl || (!ok && age >= minRefreshInterval)
+	s.mu.Unlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+	if stale {
+		if err := s.refresh(ctx); err != nil {
+			// Keep serving the last known keys if the provider is briefly unreachable
+			if key, ok := s.lookup(kid); ok {
+				return key, nil
+			}
+			return nil, err
+		}
+	}
+
+	key, ok = s.lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+func (s *RemoteKeySource) lookup(kid string) (crypto.PublicKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// refresh joins the fetch in flight or starts one, swapping the keys in once it succeeds
+func (s *RemoteKeySource) refresh(ctx context.Context) error {
+	s.mu.Lock()
+	call := s.inflight
+	if call != nil {
+		s.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	call = &keyFetch{done: make(chan struct{})}
+	s.inflight = call
+	s.mu.Unlock()
+
+	keys, err := s.fetch(ctx)
+
+	s.mu.Lock()
+	if err == nil {
+		s.keys = keys
+		s.fetchedAt = time.Now()
+	}
+	call.err = err
+	s.inflight = nil
+	s.mu.Unlock()
+	close(call.done)
+
+	return err
+}
+
+func (s *RemoteKeySource) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks %s: %w", s.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks %s: unexpected status %d", s.url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read jwks %s: %w", s.url, err)
+	}
+
+	keys, err := parseKeySet(body)
+	if err != nil {
+		return nil, fmt.Errorf("parse jwks %s: %w", s.url, err)
+	}
+	return keys, nil
+}
+
+// FileKeySource serves keys from a local JWKS file, used as a stub for offline testing
+type FileKeySource struct {
+	keys map[string]crypto.PublicKey
+}
+
+func NewFileKeySource(path string) (*FileKeySource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jwks file %s: %w", path, err)
+	}
+	keys, err := parseKeySet(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse jwks file %s: %w", path, err)
+	}
+	return &FileKeySource{keys: keys}, nil
+}
+
+func (s *FileKeySource) Key(_ context.Context, kid string) (crypto.PublicKey, error) {
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+// parseKeySet decodes a JWKS document, skipping keys of unsupported types
+func parseKeySet(data []byte) (map[string]crypto.PublicKey, error) {
+	var set jwk.JSONWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		public, err := k.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = public
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no usable signing keys")
+	}
+	return keys, nil
+}
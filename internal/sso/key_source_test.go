@@ -0,0 +1,82 @@
+package sso
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/saifoelloh/ranger/internal/jwk"
+)
+
+func TestRemoteKeySourceSharesFetch(t *testing.T) {
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(jwk.JSONWebKeySet{Keys: []jwk.JSONWebKey{jwk.NewJSONWebKey("k1", "EdDSA", public)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	source := NewRemoteKeySource(server.URL, time.Hour)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := source.Key(context.Background(), "k1")
+			errs <- err
+		}()
+	}
+
+	// The lock is free while the fetch is in flight
+	deadline := time.Now().Add(time.Second)
+	for fetches.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, ok := source.lookup("k1"); ok {
+		t.Error("lookup() found a key before the fetch finished")
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Key() error = %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("fetches = %d, want 1", got)
+	}
+
+	t.Run("waiting caller gives up with its context", func(t *testing.T) {
+		source := NewRemoteKeySource(server.URL, time.Hour)
+		source.inflight = &keyFetch{done: make(chan struct{})}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := source.Key(ctx, "k1"); err != context.Canceled {
+			t.Errorf("Key() error = %v, want %v", err, context.Canceled)
+		}
+	})
+}
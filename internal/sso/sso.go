@@ -0,0 +1,127 @@
+package sso
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/saifoelloh/ranger/internal/config"
+	"github.com/saifoelloh/ranger/internal/constant"
+	"github.com/saifoelloh/ranger/pkg/errors"
+)
+
+// Identity is what a provider vouches for once the client supplied token has been verified
+type Identity struct {
+	Platform      constant.SSOPlatform
+	Subject       string // provider user id, stored as the sso id
+	Email         string
+	EmailVerified bool
+}
+
+// Verifier checks a token issued by an SSO provider and returns the identity it proves
+type Verifier interface {
+	Verify(ctx context.Context, token string) (*Identity, error)
+}
+
+// NonceStore redeems the single-use nonces this service issued for ID tokens. Nonces are
+// stored by NonceDigest, Consume deletes whichever of the digests is stored.
+type NonceStore interface {
+	Consume(ctx context.Context, digests ...string) (bool, error)
+}
+
+// Verifiers holds the verifier of every supported platform
+type Verifiers map[constant.SSOPlatform]Verifier
+
+// NewVerifiers builds the provider verifiers from config. When SSOJWKSFile is set Google and
+// Apple use that local key set instead of fetching their published keys, and when
+// FacebookStubFile is set Facebook tokens are looked up in that file instead of the Graph API.
+func NewVerifiers(cfg config.Config, nonces NonceStore) (Verifiers, error) {
+	googleKeys, appleKeys, err := newKeySources(cfg)
+	if err != nil {
+		return nil, err
+	}
+	facebook, err := newFacebookVerifier(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return Verifiers{
+		constant.SSOPlatformGoogle: NewIDTokenVerifier(
+			constant.SSOPlatformGoogle,
+			[]string{"https://accounts.google.com", "accounts.google.com"},
+			cfg.GoogleClientIDs,
+			googleKeys,
+			nonces,
+			cfg.SSORequireNonce,
+		),
+		constant.SSOPlatformApple: NewIDTokenVerifier(
+			constant.SSOPlatformApple,
+			[]string{"https://appleid.apple.com"},
+			cfg.AppleClientIDs,
+			appleKeys,
+			nonces,
+			cfg.SSORequireNonce,
+		),
+		constant.SSOPlatformFacebook: facebook,
+	}, nil
+}
+
+// Verify dispatches the token to the verifier of the platform
+func (v Verifiers) Verify(ctx context.Context, platform constant.SSOPlatform, token string) (*Identity, error) {
+	verifier, ok := v[platform]
+	if !ok {
+		return nil, errors.BadRequest(
+			errors.WithScope("SSO"),
+			errors.WithLocation("Verify.Platform"),
+			errors.WithMessage(fmt.Sprintf("unsupported sso_platform %q", platform)),
+			errors.WithErrorCode("auth/unsupported-sso-platform"),
+		)
+	}
+	if token == "" {
+		return nil, errors.BadRequest(
+			errors.WithScope("SSO"),
+			errors.WithLocation("Verify.Token"),
+			errors.WithMessage("id_token is required for sso login"),
+			errors.WithErrorCode("auth/missing-sso-token"),
+		)
+	}
+
+	return verifier.Verify(ctx, token)
+}
+
+func newKeySources(cfg config.Config) (google, apple KeySource, err error) {
+	if cfg.SSOJWKSFile != "" {
+		keys, err := NewFileKeySource(cfg.SSOJWKSFile)
+		if err != nil {
+			return nil, nil, errors.InternalServerError(
+				errors.WithScope("SSO"),
+				errors.WithLocation("NewVerifiers.NewFileKeySource"),
+				errors.WithMessage("failed to load sso key file"),
+				errors.WithErrorCode("auth/sso-not-configured"),
+				errors.WithDetail(err.Error()),
+			)
+		}
+		return keys, keys, nil
+	}
+
+	return NewRemoteKeySource(cfg.GoogleJWKSURL, cfg.SSOJWKSCacheTTL),
+		NewRemoteKeySource(cfg.AppleJWKSURL, cfg.SSOJWKSCacheTTL),
+		nil
+}
+
+func newFacebookVerifier(cfg config.Config) (Verifier, error) {
+	if cfg.FacebookStubFile == "" {
+		return NewFacebookVerifier(cfg.FacebookGraphURL, cfg.FacebookAppID, cfg.FacebookAppSecret), nil
+	}
+
+	stub, err := NewStubFacebookVerifier(cfg.FacebookStubFile)
+	if err != nil {
+		return nil, errors.InternalServerError(
+			errors.WithScope("SSO"),
+			errors.WithLocation("NewVerifiers.NewStubFacebookVerifier"),
+			errors.WithMessage("failed to load facebook stub file"),
+			errors.WithErrorCode("auth/sso-not-configured"),
+			errors.WithDetail(err.Error()),
+		)
+	}
+	return stub, nil
+}
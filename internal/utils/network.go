@@ -0,0 +1,16 @@
+package utils
+
+import "net"
+
+// IPSubnet returns the /24 network of an IPv4 address or the /64 network of an IPv6 address,
+// empty when ip cannot be parsed
+func IPSubnet(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+	if v4 := addr.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: addr.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}).String()
+}
@@ -0,0 +1,28 @@
+package utils
+
+import "testing"
+
+func TestIPSubnet(t *testing.T) {
+	tests := []struct {
+		name string
+		ip   string
+		want string
+	}{
+		{name: "ipv4", ip: "203.0.113.77", want: "203.0.113.0/24"},
+		{name: "ipv4 network address", ip: "10.1.2.0", want: "10.1.2.0/24"},
+		{name: "ipv4 mapped ipv6", ip: "::ffff:198.51.100.9", want: "198.51.100.0/24"},
+		{name: "ipv6", ip: "2001:db8:abcd:12:1:2:3:4", want: "2001:db8:abcd:12::/64"},
+		{name: "ipv6 loopback", ip: "::1", want: "::/64"},
+		{name: "empty", ip: "", want: ""},
+		{name: "hostname", ip: "localhost", want: ""},
+		{name: "with port", ip: "203.0.113.77:443", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IPSubnet(tt.ip); got != tt.want {
+				t.Errorf("IPSubnet(%q) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+)
+
+// MaxPasswordBytes is the most bcrypt hashes, longer passwords make it fail
+const MaxPasswordBytes = 72
+
+// ValidatePassword enforces the password policy: a minimum length, at most MaxPasswordBytes
+// bytes and at least one lowercase letter, one uppercase letter and one digit
+func ValidatePassword(password string, minLength int) error {
+	if len([]rune(password)) < minLength {
+		return fmt.Errorf("password must be at least %d characters", minLength)
+	}
+	if len(password) > MaxPasswordBytes {
+		return fmt.Errorf("password must be at most %d bytes", MaxPasswordBytes)
+	}
+
+	var lower, upper, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	if !lower || !upper || !digit {
+		return errors.New("password must contain an uppercase letter, a lowercase letter and a digit")
+	}
+
+	return nil
+}
+
+// ValidatePin enforces the PIN policy: exactly length digits that are neither all the
+// same nor a straight ascending or descending run
+func ValidatePin(pin string, length int) error {
+	if len(pin) != length {
+		return fmt.Errorf("pin must be %d digits", length)
+	}
+	for _, r := range pin {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("pin must be %d digits", length)
+		}
+	}
+
+	same, ascending, descending := true, true, true
+	for i := 1; i < len(pin); i++ {
+		same = same && pin[i] == pin[i-1]
+		ascending = ascending && pin[i] == pin[i-1]+1
+		descending = descending && pin[i] == pin[i-1]-1
+	}
+	if same || ascending || descending {
+		return errors.New("pin is too easy to guess")
+	}
+
+	return nil
+}
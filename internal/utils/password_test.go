@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{name: "valid", password: "Passw0rd"},
+		{name: "too short", password: "Pass0rd", wantErr: true},
+		{name: "at the bcrypt limit", password: "Aa1" + strings.Repeat("x", MaxPasswordBytes-3)},
+		{name: "over the bcrypt limit", password: "Aa1" + strings.Repeat("x", MaxPasswordBytes-2), wantErr: true},
+		{name: "multi-byte runes over the bcrypt limit", password: "Aa1" + strings.Repeat("é", 35), wantErr: true},
+		{name: "missing upper case", password: "passw0rd", wantErr: true},
+		{name: "missing lower case", password: "PASSW0RD", wantErr: true},
+		{name: "missing digit", password: "Password", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePassword(tt.password, 8); (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePassword(%q) error = %v, wantErr %v", tt.password, err, tt.wantErr)
+			}
+		})
+	}
+}
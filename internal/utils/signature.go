@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// ParsePublicKey parses a device public key sent as PEM, or as base64 encoded DER
+// (SubjectPublicKeyInfo) or raw 32 byte Ed25519 key. Only Ed25519 and ECDSA P-256 are accepted.
+func ParsePublicKey(encoded string) (crypto.PublicKey, error) {
+	encoded = strings.TrimSpace(encoded)
+
+	var der []byte
+	if block, _ := pem.Decode([]byte(encoded)); block != nil {
+		der = block.Bytes
+	} else {
+		decoded, err := DecodeBase64(encoded)
+		if err != nil {
+			return nil, errors.New("public key is neither PEM nor base64")
+		}
+		if len(decoded) == ed25519.PublicKeySize {
+			return ed25519.PublicKey(decoded), nil
+		}
+		der = decoded
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		return k, nil
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P256() {
+			return k, nil
+		}
+	}
+	return nil, errors.New("unsupported public key type")
+}
+
+// VerifySignature checks a base64 encoded signature of message. ECDSA signatures are over the
+// SHA-256 digest and may be ASN.1 DER or the raw r||s form produced by WebCrypto.
+func VerifySignature(publicKey crypto.PublicKey, message []byte, encodedSignature string) bool {
+	signature, err := DecodeBase64(encodedSignature)
+	if err != nil {
+		return false
+	}
+
+	switch k := publicKey.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, message, signature)
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(message)
+		if len(signature) == 64 {
+			r := new(big.Int).SetBytes(signature[:32])
+			s := new(big.Int).SetBytes(signature[32:])
+			return ecdsa.Verify(k, digest[:], r, s)
+		}
+		return ecdsa.VerifyASN1(k, digest[:], signature)
+	}
+	return false
+}
+
+// DecodeBase64 accepts standard and URL-safe base64, with or without padding
+func DecodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
@@ -14,3 +14,28 @@ func GetUniqueLabel(email, ssoID *string) string {
 	}
 	return "unknown"
 }
+
+// ExtractBearerToken returns the token part of an "Authorization: Bearer <token>" header value
+func ExtractBearerToken(header string) string {
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
+
+// NormalizePhoneNumber strips formatting so the same number always hashes the same way
+func NormalizePhoneNumber(phone string) string {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(phone) {
+		if (r >= '0' && r <= '9') || (r == '+' && i == 0) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// NormalizeEmail trims and lowercases an email so the same address always hashes the same way
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,27 @@
+package utils
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		want  string
+	}{
+		{name: "already normalized", email: "jane@example.com", want: "jane@example.com"},
+		{name: "surrounding spaces", email: "  jane@example.com\n", want: "jane@example.com"},
+		{name: "mixed case", email: "Jane.Doe@Example.COM", want: "jane.doe@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeEmail(tt.email); got != tt.want {
+				t.Errorf("NormalizeEmail(%q) = %q, want %q", tt.email, got, tt.want)
+			}
+		})
+	}
+
+	if CryptoHash(NormalizeEmail("Jane@Example.com")) != CryptoHash(NormalizeEmail(" jane@example.com")) {
+		t.Error("the same address hashes differently")
+	}
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 test key of RFC 6238 appendix B, "12345678901234567890", in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTP(t *testing.T) {
+	// RFC 6238 appendix B vectors, truncated to the 6 digits authenticator apps show
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+	}
+	for _, v := range vectors {
+		step, ok := ValidateTOTP(rfc6238Secret, v.code, time.Unix(v.unix, 0), 0)
+		if !ok || step != v.unix/totpPeriod {
+			t.Errorf("ValidateTOTP(%q) at %d = (%d, %v), want (%d, true)", v.code, v.unix, step, ok, v.unix/totpPeriod)
+		}
+	}
+
+	// 1111111111 is step 37037037, 1111111109 (code 081804) is the step before
+	now := time.Unix(1111111111, 0)
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		now      time.Time
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", secret: rfc6238Secret, code: "050471", now: now, wantStep: 37037037, wantOK: true},
+		{name: "previous step within skew", secret: rfc6238Secret, code: "081804", now: now, skew: 1, wantStep: 37037036, wantOK: true},
+		{name: "previous step without skew", secret: rfc6238Secret, code: "081804", now: now},
+		{name: "next step within skew", secret: rfc6238Secret, code: "050471", now: time.Unix(1111111109, 0), skew: 1, wantStep: 37037037, wantOK: true},
+		{name: "lower case secret", secret: strings.ToLower(rfc6238Secret), code: "050471", now: now, wantStep: 37037037, wantOK: true},
+		{name: "wrong code", secret: rfc6238Secret, code: "000000", now: now, skew: 1},
+		{name: "short code", secret: rfc6238Secret, code: "05047", now: now, skew: 1},
+		{name: "long code", secret: rfc6238Secret, code: "0504710", now: now, skew: 1},
+		{name: "invalid secret", secret: "not base32!", code: "050471", now: now, skew: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(tt.secret, tt.code, tt.now, tt.skew)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP() = (%d, %v), want (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"strings"
 
 	"github.com/mssola/useragent"
+	"github.com/saifoelloh/ranger/internal/constant"
 )
 
 type UADeviceInfo struct {
 	Device string
 	OS     string
+	Class  constant.DeviceClass
 }
 
 func ParseUserAgent(userAgent string) UADeviceInfo {
@@ -49,5 +51,21 @@ func ParseUserAgent(userAgent string) UADeviceInfo {
 	return UADeviceInfo{
 		Device: device,
 		OS:     os,
+		Class:  deviceClass(ua, userAgent),
+	}
+}
+
+func deviceClass(ua *useragent.UserAgent, userAgent string) constant.DeviceClass {
+	lower := strings.ToLower(userAgent)
+	switch {
+	case strings.TrimSpace(userAgent) == "":
+		return constant.DeviceClassUnknown
+	case strings.Contains(lower, "ipad") || strings.Contains(lower, "tablet"),
+		strings.Contains(lower, "android") && !strings.Contains(lower, "mobile"):
+		return constant.DeviceClassTablet
+	case ua.Mobile():
+		return constant.DeviceClassMobile
+	default:
+		return constant.DeviceClassDesktop
 	}
 }
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/mssola/useragent"
+	"github.com/saifoelloh/ranger/internal/constant"
+)
+
+func TestDeviceClass(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      constant.DeviceClass
+	}{
+		{name: "empty", userAgent: "", want: constant.DeviceClassUnknown},
+		{name: "blank", userAgent: "   ", want: constant.DeviceClassUnknown},
+		{
+			name:      "iphone",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			want:      constant.DeviceClassMobile,
+		},
+		{
+			name:      "android phone",
+			userAgent: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+			want:      constant.DeviceClassMobile,
+		},
+		{
+			name:      "ipad",
+			userAgent: "Mozilla/5.0 (iPad; CPU OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			want:      constant.DeviceClassTablet,
+		},
+		{
+			name:      "android tablet without mobile token",
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-X710) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			want:      constant.DeviceClassTablet,
+		},
+		{
+			name:      "tablet token",
+			userAgent: "Mozilla/5.0 (Tablet; rv:26.0) Gecko/26.0 Firefox/26.0",
+			want:      constant.DeviceClassTablet,
+		},
+		{
+			name:      "windows desktop",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+			want:      constant.DeviceClassDesktop,
+		},
+		{
+			name:      "mac desktop",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15",
+			want:      constant.DeviceClassDesktop,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deviceClass(useragent.New(tt.userAgent), tt.userAgent); got != tt.want {
+				t.Errorf("deviceClass(%q) = %s, want %s", tt.userAgent, got, tt.want)
+			}
+		})
+	}
+}
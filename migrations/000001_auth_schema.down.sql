@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS "AuditLogs";
+DROP TABLE IF EXISTS "PasswordHistories";
+DROP TABLE IF EXISTS "UserPins";
+DROP TABLE IF EXISTS "RecoveryCodes";
+DROP TABLE IF EXISTS "UserTotps";
+DROP TABLE IF EXISTS "RefreshTokens";
+
+DROP INDEX IF EXISTS "Sessions_user_id_active_idx";
+ALTER TABLE "Sessions" DROP COLUMN IF EXISTS location;
+
+DROP INDEX IF EXISTS "Users_facebook_sso_id_key";
+DROP INDEX IF EXISTS "Users_apple_sso_id_key";
+DROP INDEX IF EXISTS "Users_google_sso_id_key";
+DROP INDEX IF EXISTS "Users_phone_number_hash_key";
+DROP INDEX IF EXISTS "Users_email_hash_key";
+-- Columns the service only started to use are kept, older clients may rely on them
+ALTER TABLE "Users" DROP COLUMN IF EXISTS email_verification_expires_at;
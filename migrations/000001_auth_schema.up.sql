@@ -0,0 +1,102 @@
+-- Columns of the existing "Users" and "Sessions" tables the service reads or writes
+ALTER TABLE "Users"
+    ADD COLUMN IF NOT EXISTS role VARCHAR(32) NOT NULL DEFAULT 'INVESTOR',
+    ADD COLUMN IF NOT EXISTS is_deleted BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS email_verification_code VARCHAR(128),
+    ADD COLUMN IF NOT EXISTS email_verification_expires_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS phone_number_hash VARCHAR(128),
+    ADD COLUMN IF NOT EXISTS phone_number_verified BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS last_change_password TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS know_from VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS facebook_sso_id VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS "createdAt" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS "updatedAt" TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- Deleted accounts keep their row, so uniqueness only covers live accounts
+CREATE UNIQUE INDEX IF NOT EXISTS "Users_email_hash_key" ON "Users" (email_hash) WHERE is_deleted = false;
+CREATE UNIQUE INDEX IF NOT EXISTS "Users_phone_number_hash_key" ON "Users" (phone_number_hash) WHERE is_deleted = false;
+CREATE UNIQUE INDEX IF NOT EXISTS "Users_google_sso_id_key" ON "Users" (google_sso_id) WHERE is_deleted = false;
+CREATE UNIQUE INDEX IF NOT EXISTS "Users_apple_sso_id_key" ON "Users" (apple_sso_id) WHERE is_deleted = false;
+CREATE UNIQUE INDEX IF NOT EXISTS "Users_facebook_sso_id_key" ON "Users" (facebook_sso_id) WHERE is_deleted = false;
+
+ALTER TABLE "Sessions"
+    ADD COLUMN IF NOT EXISTS location VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS "createdAt" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS "updatedAt" TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS "Sessions_user_id_active_idx" ON "Sessions" (user_id, active);
+
+-- Refresh tokens are stored hashed, every rotation adds a row to the family of the first token
+CREATE TABLE IF NOT EXISTS "RefreshTokens" (
+    id UUID PRIMARY KEY,
+    session_id UUID NOT NULL REFERENCES "Sessions" (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES "Users" (id) ON DELETE CASCADE,
+    family_id UUID NOT NULL,
+    parent_id UUID REFERENCES "RefreshTokens" (id) ON DELETE SET NULL,
+    token_hash VARCHAR(128) NOT NULL,
+    revoked BOOLEAN NOT NULL DEFAULT false,
+    used_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ NOT NULL,
+    "createdAt" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    "updatedAt" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "RefreshTokens_token_hash_key" ON "RefreshTokens" (token_hash);
+CREATE INDEX IF NOT EXISTS "RefreshTokens_family_id_idx" ON "RefreshTokens" (family_id);
+CREATE INDEX IF NOT EXISTS "RefreshTokens_session_id_idx" ON "RefreshTokens" (session_id);
+CREATE INDEX IF NOT EXISTS "RefreshTokens_user_id_idx" ON "RefreshTokens" (user_id);
+
+-- Secrets are encrypted with MFA_ENCRYPTION_KEY, pending_secret holds an enrollment until it is activated
+CREATE TABLE IF NOT EXISTS "UserTotps" (
+    user_id UUID PRIMARY KEY REFERENCES "Users" (id) ON DELETE CASCADE,
+    secret TEXT NOT NULL DEFAULT '',
+    pending_secret TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    "createdAt" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    "updatedAt" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS "RecoveryCodes" (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES "Users" (id) ON DELETE CASCADE,
+    code_hash VARCHAR(128) NOT NULL,
+    used_at TIMESTAMPTZ,
+    "createdAt" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "RecoveryCodes_user_id_code_hash_key" ON "RecoveryCodes" (user_id, code_hash);
+
+CREATE TABLE IF NOT EXISTS "UserPins" (
+    user_id UUID PRIMARY KEY REFERENCES "Users" (id) ON DELETE CASCADE,
+    pin VARCHAR(72) NOT NULL,
+    "createdAt" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    "updatedAt" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS "PasswordHistories" (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES "Users" (id) ON DELETE CASCADE,
+    password VARCHAR(72) NOT NULL,
+    "createdAt" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS "PasswordHistories_user_id_createdAt_idx" ON "PasswordHistories" (user_id, "createdAt" DESC);
+
+-- Audit entries outlive the accounts they mention, so they carry no foreign keys
+CREATE TABLE IF NOT EXISTS "AuditLogs" (
+    id UUID PRIMARY KEY,
+    actor_id VARCHAR(64) NOT NULL,
+    actor_role VARCHAR(32) NOT NULL,
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32) NOT NULL,
+    target_id VARCHAR(255) NOT NULL,
+    reason TEXT,
+    detail JSONB NOT NULL DEFAULT '{}',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    "createdAt" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS "AuditLogs_target_idx" ON "AuditLogs" (target_type, target_id, "createdAt" DESC);
+CREATE INDEX IF NOT EXISTS "AuditLogs_actor_id_idx" ON "AuditLogs" (actor_id, "createdAt" DESC);
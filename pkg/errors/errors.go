@@ -19,7 +19,7 @@ type Extension struct {
 	Location      string            `json:"location,omitempty"`
 	ErrorCode     string            `json:"error_code,omitempty"`
 	StatusCode    int               `json:"status_code"`
-	Extra         map[string]string `json:"-"`
+	Extra         map[string]string `json:"-"` // sent as response headers
 }
 
 func (e *Extension) Error() string {
@@ -85,6 +85,14 @@ func Unauthorized(opts ...Option) error {
 	return e
 }
 
+func Forbidden(opts ...Option) error {
+	e := &Extension{StatusCode: http.StatusForbidden}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
 func NotFound(opts ...Option) error {
 	e := &Extension{StatusCode: http.StatusNotFound}
 	for _, opt := range opts {
@@ -101,6 +109,14 @@ func InternalServerError(opts ...Option) error {
 	return e
 }
 
+func Conflict(opts ...Option) error {
+	e := &Extension{StatusCode: http.StatusConflict}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
 func TooManyRequests(opts ...Option) error {
 	e := &Extension{StatusCode: http.StatusTooManyRequests}
 	for _, opt := range opts {
@@ -109,6 +125,14 @@ func TooManyRequests(opts ...Option) error {
 	return e
 }
 
+func ServiceUnavailable(opts ...Option) error {
+	e := &Extension{StatusCode: http.StatusServiceUnavailable}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
 func LogAndPanic(err error) {
 	if extErr, ok := err.(*Extension); ok {
 		log.Printf("[FATAL] %s/%s - %s\n%s",